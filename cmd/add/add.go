@@ -1,7 +1,13 @@
 package add
 
 import (
+	"errors"
+	"fmt"
+
+	"github.com/alex-way/changesets/pkg/changelog"
 	"github.com/alex-way/changesets/pkg/changeset"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/project"
 	"github.com/alex-way/changesets/pkg/version"
 	"github.com/charmbracelet/huh"
 	"github.com/urfave/cli/v2"
@@ -26,26 +32,25 @@ func getMessageOrPrompt(cCtx *cli.Context) (string, error) {
 	return message, nil
 }
 
-func getBumpTypeOrPrompt(cCtx *cli.Context) (version.BumpType, error) {
-	type_ := cCtx.String("type")
+func getBumpTypeOrPrompt(cCtx *cli.Context, vocabulary version.Vocabulary) (version.BumpType, error) {
+	type_ := cCtx.String("bump-type")
 	var bump_type version.BumpType
 
 	if type_ != "" {
-		parsed_type, err := version.ParseBumpType(type_)
+		parsed_type, err := vocabulary.Parse(type_)
 		if err != nil {
 			return 0, cli.Exit(err, 1)
 		}
 		bump_type = parsed_type
 	} else {
+		var options []huh.Option[version.BumpType]
+		for _, option := range version.BUMP_TYPES {
+			options = append(options, huh.NewOption(vocabulary.Label(option), option))
+		}
 
 		err := huh.NewSelect[version.BumpType]().
 			Title("Type of change").
-			Options(
-				huh.NewOption("Major", version.Major),
-				huh.NewOption("Minor", version.Minor),
-				huh.NewOption("Patch", version.Patch),
-				huh.NewOption("Other", version.None),
-			).
+			Options(options...).
 			Value(&bump_type).
 			Run()
 
@@ -56,8 +61,42 @@ func getBumpTypeOrPrompt(cCtx *cli.Context) (version.BumpType, error) {
 	return bump_type, nil
 }
 
+// Regenerates the unreleased section of the changelog from all pending changesets when enabled in the config
+func updateUnreleased(_project project.Project) error {
+	_config, err := _project.GetConfig()
+	if errors.Is(err, config.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	changes, err := _project.GetChanges()
+	if err != nil {
+		return err
+	}
+	filename, updated, err := changelog.UpdateUnreleased(_project.Root, _config, changes)
+	if err != nil {
+		return fmt.Errorf("failed to update changelog: %w", err)
+	}
+	if updated {
+		println("Updated the unreleased section of " + filename)
+	}
+	return nil
+}
+
 func Run(cCtx *cli.Context) error {
-	bump_type, err := getBumpTypeOrPrompt(cCtx)
+	_project, err := project.Resolve(cCtx.String("cwd"), cCtx.String("config"), cCtx.StringSlice("set"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	opts, err := _project.ChangesetOptions()
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	bump_type, err := getBumpTypeOrPrompt(cCtx, opts.Vocabulary)
 	if err != nil {
 		return cli.Exit(err, 1)
 	}
@@ -66,12 +105,17 @@ func Run(cCtx *cli.Context) error {
 		return cli.Exit(err, 1)
 	}
 
-	changeset_filepath, err := changeset.CreateChangeFile(bump_type, message)
+	changeset_filepath, err := changeset.CreateChangeFile(_project.Root, bump_type, message)
 	if err != nil {
 		return cli.Exit(err, 1)
 	}
 
 	println("Created changeset " + changeset_filepath)
+
+	if err := updateUnreleased(_project); err != nil {
+		return cli.Exit(err, 1)
+	}
+
 	println("You can now edit the file and commit it to version control.")
 	return nil
 }
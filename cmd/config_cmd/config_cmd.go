@@ -0,0 +1,77 @@
+package config_cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/project"
+	"github.com/urfave/cli/v2"
+)
+
+func printJSON(value interface{}) error {
+	contents, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(contents))
+	return nil
+}
+
+// Prints each field which was set along with the layer which set it
+func printOrigins(resolved config.Resolved) error {
+	for _, field := range resolved.Fields() {
+		value, err := json.Marshal(resolved.Value(field))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s = %s (%s)\n", field, value, resolved.Origins[field])
+	}
+	return nil
+}
+
+// Prints the effective config after all layers have been applied and validated
+func Show(cCtx *cli.Context) error {
+	_project, err := project.Resolve(cCtx.String("cwd"), cCtx.String("config"), cCtx.StringSlice("set"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	resolved, err := _project.ResolveConfig()
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	println("Project root: " + _project.Root)
+	if resolved.Path != "" {
+		println("Config file: " + resolved.Path)
+	}
+	if resolved.LocalPath != "" {
+		println("Local config file: " + resolved.LocalPath)
+	}
+	for _, base := range resolved.Bases {
+		println("Extends: " + base)
+	}
+
+	if cCtx.Bool("origin") {
+		err = printOrigins(resolved)
+	} else {
+		err = printJSON(resolved.Config)
+	}
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if err := resolved.Config.Validate(_project.Root); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+// Prints the JSON Schema of the config file
+func Schema(cCtx *cli.Context) error {
+	if err := printJSON(config.Schema()); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
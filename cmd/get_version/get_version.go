@@ -9,24 +9,25 @@ import (
 
 	"github.com/alex-way/changesets/pkg/config"
 	"github.com/alex-way/changesets/pkg/plugin"
+	"github.com/alex-way/changesets/pkg/project"
 	"github.com/alex-way/changesets/pkg/version"
 )
 
-func GetVersion() (version.Version, error) {
-	_config, err := config.GetConfig()
+// Reads the version from the versioned file of a single plugin
+func GetTargetVersion(_project project.Project, _config config.Config, _plugin config.Plugin) (version.Version, error) {
+	handler := wasm.NewClient(_plugin, _project.Root, _config)
+	client := plugin.NewVersionGetterSetterServiceClient(handler)
+
+	settings, err := plugin.NewSettings(_plugin.Settings)
 	if err != nil {
 		return version.Version{}, err
 	}
 
-	handler := &wasm.Runner{
-		Plugin: _config.Plugin,
-	}
-	client := plugin.NewVersionGetterSetterServiceClient(handler)
-
 	req := &plugin.RequestMessage{
 		Request: &plugin.RequestMessage_GetVersion{
 			GetVersion: &plugin.GetVersionRequest{
-				FilePath: _config.Plugin.VersionedFile,
+				FilePath: _plugin.VersionedFile,
+				Settings: settings,
 			},
 		},
 	}
@@ -47,8 +48,26 @@ func GetVersion() (version.Version, error) {
 	return version.ParseVersion(unparsed_version)
 }
 
+// Reads the version from the source of truth, which is the plugin or the source target
+func GetVersion(_project project.Project) (version.Version, error) {
+	_config, err := _project.GetConfig()
+	if err != nil {
+		return version.Version{}, err
+	}
+	if err := _config.Validate(_project.Root); err != nil {
+		return version.Version{}, err
+	}
+
+	return GetTargetVersion(_project, _config, _config.Source())
+}
+
 func Run(cCtx *cli.Context) error {
-	version, err := GetVersion()
+	_project, err := project.Resolve(cCtx.String("cwd"), cCtx.String("config"), cCtx.StringSlice("set"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	version, err := GetVersion(_project)
 	if err != nil {
 		return cli.Exit(err, 1)
 	}
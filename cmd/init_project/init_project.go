@@ -0,0 +1,207 @@
+package init_project
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alex-way/changesets/cmd/get_version"
+	wasm "github.com/alex-way/changesets/pkg"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/project"
+	"github.com/alex-way/changesets/pkg/version"
+	"github.com/charmbracelet/huh"
+	"github.com/urfave/cli/v2"
+)
+
+const DEFAULT_PLUGIN_NAME string = "versionfile"
+const DEFAULT_PLUGIN_URL string = "https://github.com/alex-way/changesets-go-versionfile-plugin/releases/download/0.0.2/versionfile.wasm"
+const DEFAULT_VERSIONED_FILE string = ".changeset/version"
+const DEFAULT_INITIAL_VERSION string = "0.0.0"
+
+var Flags = []cli.Flag{
+	&cli.StringFlag{Name: "plugin-name", Value: DEFAULT_PLUGIN_NAME},
+	&cli.StringFlag{Name: "plugin-url", Value: DEFAULT_PLUGIN_URL},
+	&cli.StringFlag{Name: "sha256", Usage: "the expected sha256 of the plugin, calculated by fetching the plugin when not set"},
+	&cli.StringFlag{Name: "versioned-file", Value: DEFAULT_VERSIONED_FILE},
+	&cli.StringFlag{Name: "initial-version", Value: DEFAULT_INITIAL_VERSION, Usage: "the version written to the versioned file when it doesn't exist"},
+	&cli.StringFlag{Name: "format", Value: "json", Usage: "the config file format, one of json, toml or yaml"},
+	&cli.BoolFlag{Name: "non-interactive", Aliases: []string{"y"}, Usage: "use the flag values without prompting"},
+	&cli.BoolFlag{Name: "skip-version-check", Usage: "don't read the current version using the plugin"},
+	&cli.BoolFlag{Name: "force", Usage: "overwrite an existing config file"},
+}
+
+// Returns the flag value, prompting for it unless running non-interactively
+func getValueOrPrompt(cCtx *cli.Context, name string, title string) (string, error) {
+	value := cCtx.String(name)
+	if cCtx.Bool("non-interactive") || cCtx.IsSet(name) {
+		return value, nil
+	}
+
+	err := huh.NewInput().
+		Title(title).
+		Value(&value).
+		Run()
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func getConfigPath(cCtx *cli.Context, _project project.Project) (string, error) {
+	if _project.ConfigPath != "" {
+		return _project.ConfigPath, nil
+	}
+
+	format := cCtx.String("format")
+	switch format {
+	case "json", "toml", "yaml":
+		return _project.Path(config.CHANGESET_DIRECTORY, "config."+format), nil
+	}
+	return "", fmt.Errorf("unsupported format %q, must be one of: json, toml, yaml", format)
+}
+
+func checkExistingConfig(_project project.Project, config_path string) error {
+	if _, err := os.Stat(config_path); err == nil {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", config_path)
+	}
+	if _project.ConfigPath != "" {
+		return nil
+	}
+	found, err := config.HasConfig(_project.Root)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("a config file already exists in %s, use --force to overwrite it", _project.Root)
+	}
+	return nil
+}
+
+func getPlugin(cCtx *cli.Context, _project project.Project) (config.Plugin, error) {
+	var _plugin config.Plugin
+	var err error
+
+	if _plugin.Name, err = getValueOrPrompt(cCtx, "plugin-name", "Plugin name"); err != nil {
+		return config.Plugin{}, err
+	}
+	if _plugin.URL, err = getValueOrPrompt(cCtx, "plugin-url", "Plugin URL (https://, file:// or exec://)"); err != nil {
+		return config.Plugin{}, err
+	}
+	if _plugin.VersionedFile, err = getValueOrPrompt(cCtx, "versioned-file", "File containing the version"); err != nil {
+		return config.Plugin{}, err
+	}
+
+	_plugin.SHA256 = cCtx.String("sha256")
+	if _plugin.SHA256 == "" {
+		println("Fetching " + _plugin.URL + " to pin its sha256...")
+		runner := wasm.NewClient(_plugin, _project.Root, config.Config{})
+		_plugin.SHA256, err = runner.Checksum(context.Background())
+		if err != nil {
+			return config.Plugin{}, fmt.Errorf("failed to fetch plugin: %w", err)
+		}
+	}
+	return _plugin, nil
+}
+
+// Creates the versioned file containing just the initial version when it doesn't exist. That's only the format of the
+// default versionfile plugin, so the file must already exist for other plugins. Returns whether the file was created
+func createVersionedFile(_project project.Project, _plugin config.Plugin, initial_version string) (bool, error) {
+	path := _project.Path(_plugin.VersionedFile)
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+	if _plugin.URL != DEFAULT_PLUGIN_URL {
+		return false, fmt.Errorf("%s doesn't exist. Create it containing the current version in the format the %s plugin reads, then run init again", _plugin.VersionedFile, _plugin.Name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(path, []byte(initial_version+"\n"), 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func shouldCheckVersion(cCtx *cli.Context) (bool, error) {
+	if cCtx.Bool("skip-version-check") {
+		return false, nil
+	}
+	if cCtx.Bool("non-interactive") {
+		return true, nil
+	}
+
+	check := true
+	err := huh.NewConfirm().
+		Title("Read the current version using the plugin?").
+		Value(&check).
+		Run()
+	return check, err
+}
+
+func Run(cCtx *cli.Context) error {
+	_project, err := project.Resolve(cCtx.String("cwd"), cCtx.String("config"), cCtx.StringSlice("set"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	config_path, err := getConfigPath(cCtx, _project)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	initial_version, err := version.ParseVersion(cCtx.String("initial-version"))
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("--initial-version %q must be a version such as 1.0.0", cCtx.String("initial-version")), 1)
+	}
+
+	if !cCtx.Bool("force") {
+		if err := checkExistingConfig(_project, config_path); err != nil {
+			return cli.Exit(err, 1)
+		}
+	}
+
+	_plugin, err := getPlugin(cCtx, _project)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	created, err := createVersionedFile(_project, _plugin, initial_version.String())
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config_path), 0755); err != nil {
+		return cli.Exit(err, 1)
+	}
+	if err := os.MkdirAll(_project.Path(config.CHANGESET_DIRECTORY), 0755); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if err := config.WriteConfig(config_path, config.Config{Plugin: _plugin}); err != nil {
+		return cli.Exit(err, 1)
+	}
+	println("Created " + config_path)
+	if created {
+		println(fmt.Sprintf("Created %s with version %s", _plugin.VersionedFile, initial_version.String()))
+	}
+
+	check, err := shouldCheckVersion(cCtx)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	if check {
+		_project.ConfigPath = config_path
+		current_version, err := get_version.GetVersion(_project)
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("The plugin could not read the version from %s: %v", _plugin.VersionedFile, err), 1)
+		}
+		println("The current version is " + current_version.String())
+	}
+
+	return nil
+}
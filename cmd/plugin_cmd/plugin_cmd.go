@@ -0,0 +1,217 @@
+package plugin_cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	wasm "github.com/alex-way/changesets/pkg"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/lock"
+	"github.com/alex-way/changesets/pkg/plugin/plugintest"
+	"github.com/alex-way/changesets/pkg/project"
+	"github.com/urfave/cli/v2"
+)
+
+func resolveProject(cCtx *cli.Context) (project.Project, config.Resolved, error) {
+	_project, err := project.Resolve(cCtx.String("cwd"), cCtx.String("config"), cCtx.StringSlice("set"))
+	if err != nil {
+		return project.Project{}, config.Resolved{}, err
+	}
+	resolved, err := _project.ResolveConfig()
+	if err != nil {
+		return project.Project{}, config.Resolved{}, err
+	}
+	return _project, resolved, nil
+}
+
+// Installs every plugin and rewrites the lockfile from them. When updating, plugins are fetched regardless of their
+// pinned checksum, which is then updated in the config file configuring the plugin, or printed to be pinned by hand
+// when that file can't be edited
+func install(cCtx *cli.Context, update bool) error {
+	_project, resolved, err := resolveProject(cCtx)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var _lock lock.Lock
+	for i, _plugin := range resolved.Config.Plugins() {
+		installed, err := wasm.NewClient(_plugin, _project.Root, resolved.Config).Install(context.Background(), update)
+		if err != nil {
+			return cli.Exit(fmt.Errorf("failed to install plugin %s: %w", _plugin.Name, err), 1)
+		}
+
+		if update && installed.SHA256 != _plugin.SHA256 {
+			path, err := resolved.PinChecksum(i, installed.SHA256)
+			if err != nil {
+				println(fmt.Sprintf("Couldn't pin %s: %s", _plugin.Name, err))
+				println(fmt.Sprintf("Set its sha256 to %s to pin it", installed.SHA256))
+			} else {
+				println(fmt.Sprintf("Pinned %s to %s in %s", _plugin.Name, installed.SHA256, path))
+			}
+		}
+
+		_lock.Plugins = append(_lock.Plugins, lock.Plugin{
+			Name:   _plugin.Name,
+			URL:    _plugin.URL,
+			SHA256: installed.SHA256,
+		})
+		println(fmt.Sprintf("Installed %s (%s)", _plugin.Name, installed.SHA256))
+	}
+
+	if err := _lock.Write(_project.LockPath()); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+// Fetches every plugin into the cache and records them in the lockfile
+func Install(cCtx *cli.Context) error {
+	return install(cCtx, false)
+}
+
+// Fetches the latest version of every plugin from its URL, pinning the new checksums in the config
+func Update(cCtx *cli.Context) error {
+	return install(cCtx, true)
+}
+
+// Describes whether the plugin is available locally
+func cacheStatus(_project project.Project, _plugin config.Plugin, sum string) string {
+	if _plugin.IsExec() {
+		actual, err := wasm.NewClient(_plugin, _project.Root, config.Config{}).Checksum(context.Background())
+		switch {
+		case err != nil:
+			return "missing"
+		case sum != "" && actual != sum:
+			return "modified"
+		}
+		return "executable"
+	}
+
+	if sum == "" {
+		return "not installed"
+	}
+	_, actual, err := wasm.CheckCached(sum)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return "not cached"
+	case err != nil:
+		return err.Error()
+	case actual != sum:
+		return "modified"
+	}
+	return "cached"
+}
+
+// Prints every plugin along with its pinned and locked checksums and whether it's cached
+func List(cCtx *cli.Context) error {
+	_project, resolved, err := resolveProject(cCtx)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	_lock, err := lock.Read(_project.LockPath())
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	for _, _plugin := range resolved.Config.Plugins() {
+		locked, _ := _lock.Find(_plugin.Name, _plugin.URL)
+		sum := _plugin.SHA256
+		if sum == "" {
+			sum = locked.SHA256
+		}
+
+		fmt.Printf("%s\n", _plugin.Name)
+		fmt.Printf("  url: %s\n", _plugin.URL)
+		fmt.Printf("  pinned: %s\n", valueOrNone(_plugin.SHA256))
+		fmt.Printf("  locked: %s\n", valueOrNone(locked.SHA256))
+		fmt.Printf("  status: %s\n", cacheStatus(_project, _plugin, sum))
+	}
+	return nil
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// Re-hashes the installed plugins, reporting any which don't match the lockfile or the config
+func Verify(cCtx *cli.Context) error {
+	_project, resolved, err := resolveProject(cCtx)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	_lock, err := lock.Read(_project.LockPath())
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var problems []string
+	plugins := resolved.Config.Plugins()
+	for _, _plugin := range plugins {
+		locked, ok := _lock.Find(_plugin.Name, _plugin.URL)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is not in %s, run `changeset plugin install`", _plugin.Name, lock.LOCKFILE_NAME))
+			continue
+		}
+		if _plugin.SHA256 != "" && _plugin.SHA256 != locked.SHA256 {
+			problems = append(problems, fmt.Sprintf("%s is pinned to %s in the config but locked to %s", _plugin.Name, _plugin.SHA256, locked.SHA256))
+		}
+
+		switch status := cacheStatus(_project, _plugin, locked.SHA256); status {
+		case "cached", "executable":
+		case "modified":
+			problems = append(problems, fmt.Sprintf("%s has been modified since it was installed, it no longer matches %s", _plugin.Name, locked.SHA256))
+		default:
+			problems = append(problems, fmt.Sprintf("%s is %s, run `changeset plugin install`", _plugin.Name, status))
+		}
+	}
+
+	if len(problems) > 0 {
+		return cli.Exit("Plugin verification failed:\n  - "+strings.Join(problems, "\n  - "), 1)
+	}
+	println(fmt.Sprintf("All %d plugins are verified.", len(plugins)))
+	return nil
+}
+
+// Runs the conformance suite against a plugin, e.g. `changeset plugin test plugin.wasm --fixture package.json`
+func Test(cCtx *cli.Context) error {
+	if cCtx.NArg() != 1 {
+		return cli.Exit("expected the path or URL of the plugin to test", 1)
+	}
+	fixture_path := cCtx.String("fixture")
+	fixture, err := os.ReadFile(fixture_path)
+	if err != nil {
+		return cli.Exit(fmt.Errorf("failed to read the fixture: %w", err), 1)
+	}
+
+	options := plugintest.Options{
+		URL:           cCtx.Args().First(),
+		VersionedFile: cCtx.String("versioned-file"),
+		Fixture:       string(fixture),
+		Version:       cCtx.String("expect-version"),
+	}
+	if options.VersionedFile == "" {
+		options.VersionedFile = filepath.Base(fixture_path)
+	}
+	if settings := cCtx.String("settings"); settings != "" {
+		if err := json.Unmarshal([]byte(settings), &options.Settings); err != nil {
+			return cli.Exit(fmt.Errorf("--settings must be a JSON object: %w", err), 1)
+		}
+	}
+
+	passed, err := plugintest.Report(cCtx.Context, os.Stdout, options)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	if !passed {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package preview
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alex-way/changesets/cmd/get_version"
+	"github.com/alex-way/changesets/pkg/changelog"
+	"github.com/alex-way/changesets/pkg/changeset"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/project"
+	"github.com/alex-way/changesets/pkg/version"
+	"github.com/urfave/cli/v2"
+)
+
+func Run(cCtx *cli.Context) error {
+	_project, err := project.Resolve(cCtx.String("cwd"), cCtx.String("config"), cCtx.StringSlice("set"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	changes, err := _project.GetChanges()
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if len(changes) == 0 {
+		println("No changesets found. Please run 'changeset add' to add changes.")
+		return nil
+	}
+
+	current_version, err := get_version.GetVersion(_project)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	_changeset := changeset.Changeset{
+		CurrentVersion: current_version,
+		Changes:        changes,
+	}
+
+	if _changeset.DetermineFinalBumpType() <= version.None {
+		println(fmt.Sprintf("The version will remain at %s as all changes are not version impacting.", _changeset.CurrentVersion.String()))
+		return nil
+	}
+
+	_config, err := _project.GetConfig()
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	changelog_config := config.Changelog{}
+	if _config.Changelog != nil {
+		changelog_config = *_config.Changelog
+	}
+
+	vocabulary, err := _config.Vocabulary()
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	next_version := _changeset.DetermineNextVersion()
+	release, err := changelog.Build(_project.Root, next_version, time.Now(), changes, changelog_config)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	println(release.Render(changelog_config, vocabulary))
+
+	if guide := changelog.RenderMigrationGuide(next_version, changes); guide != "" {
+		println(guide)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alex-way/changesets/pkg/changelog"
+	"github.com/alex-way/changesets/pkg/changeset"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/project"
+	"github.com/urfave/cli/v2"
+)
+
+func Run(cCtx *cli.Context) error {
+	_project, err := project.Resolve(cCtx.String("cwd"), cCtx.String("config"), cCtx.StringSlice("set"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	changes, err := _project.GetChanges()
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	_config, err := _project.GetConfig()
+	if err != nil && !errors.Is(err, config.ErrNotFound) {
+		return cli.Exit(err, 1)
+	}
+	if err == nil {
+		if err := _config.Validate(_project.Root); err != nil {
+			return cli.Exit(err, 1)
+		}
+	}
+
+	if _config.Migration != nil {
+		missing := changelog.MissingMigrations(changes, *_config.Migration)
+		if len(missing) > 0 {
+			lines := []string{"The following major changesets are missing a \"" + changeset.MIGRATION_HEADING + "\" section:"}
+			for _, change := range missing {
+				lines = append(lines, "  - "+change.FilePath)
+			}
+			return cli.Exit(strings.Join(lines, "\n"), 1)
+		}
+	}
+
+	println(fmt.Sprintf("All %d changesets are valid.", len(changes)))
+	return nil
+}
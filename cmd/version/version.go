@@ -2,33 +2,39 @@ package version
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/alex-way/changesets/cmd/get_version"
 	wasm "github.com/alex-way/changesets/pkg"
+	"github.com/alex-way/changesets/pkg/changelog"
 	"github.com/alex-way/changesets/pkg/changeset"
 	"github.com/alex-way/changesets/pkg/config"
 	"github.com/alex-way/changesets/pkg/plugin"
+	"github.com/alex-way/changesets/pkg/project"
 	"github.com/alex-way/changesets/pkg/version"
 	"github.com/urfave/cli/v2"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
-func setVersion(version version.Version) error {
-	_config, err := config.GetConfig()
-	if err != nil {
-		return cli.Exit(err, 1)
-	}
+func setVersion(_project project.Project, _config config.Config, _plugin config.Plugin, version version.Version) error {
+	handler := wasm.NewClient(_plugin, _project.Root, _config)
+	client := plugin.NewVersionGetterSetterServiceClient(handler)
 
-	handler := &wasm.Runner{
-		Plugin: _config.Plugin,
+	settings, err := plugin.NewSettings(_plugin.Settings)
+	if err != nil {
+		return err
 	}
-	client := plugin.NewVersionGetterSetterServiceClient(handler)
 
 	req := &plugin.RequestMessage{
 		Request: &plugin.RequestMessage_SetVersion{
 			SetVersion: &plugin.SetVersionRequest{
-				FilePath: _config.Plugin.VersionedFile,
+				FilePath: _plugin.VersionedFile,
 				Version:  version.String(),
+				Settings: settings,
 			},
 		},
 	}
@@ -36,22 +42,223 @@ func setVersion(version version.Version) error {
 	ctx := context.Background()
 	resp, err := client.Request(ctx, req)
 	if err != nil {
-		message := fmt.Sprintf("failed to set version: %v", err)
-		return cli.Exit(message, 1)
+		return fmt.Errorf("failed to set version in %s: %v", _plugin.VersionedFile, err)
 	}
 
 	if resp.Status.Code != 0 {
-		message := fmt.Sprintf(resp.Status.Message)
-		return cli.Exit(message, 1)
+		return fmt.Errorf("failed to set version in %s: %s", _plugin.VersionedFile, resp.Status.Message)
 	}
 
-	println(("You got it boss"))
+	println("Updated " + _plugin.VersionedFile)
 
 	return nil
 }
 
+// Sets the version in every versioned file. When any plugin fails, the files already written are restored, so that
+// the targets are never left out of sync
+func setVersions(_project project.Project, _config config.Config, next_version version.Version) error {
+	originals := map[string][]byte{}
+	restore := func() {
+		for path, contents := range originals {
+			if err := os.WriteFile(path, contents, 0644); err != nil {
+				slog.Error("failed to restore versioned file", "file", path, "error", err)
+			}
+		}
+	}
+
+	for _, _plugin := range _config.Plugins() {
+		path := _project.Path(_plugin.VersionedFile)
+		if _, ok := originals[path]; !ok {
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				restore()
+				return fmt.Errorf("failed to read %s: %w", _plugin.VersionedFile, err)
+			}
+			originals[path] = contents
+		}
+
+		if err := setVersion(_project, _config, _plugin, next_version); err != nil {
+			restore()
+			return err
+		}
+	}
+	return nil
+}
+
+// Reads the version of every target other than the source, so that nothing is written unless they can all be read
+func checkTargets(_project project.Project, _config config.Config, current_version version.Version) error {
+	if len(_config.Targets) == 0 {
+		return nil
+	}
+
+	source := _config.Source()
+	for _, target := range _config.Targets {
+		if target.VersionedFile == source.VersionedFile && target.URL == source.URL {
+			continue
+		}
+		target_version, err := get_version.GetTargetVersion(_project, _config, target)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", target.VersionedFile, err)
+		}
+		if target_version != current_version {
+			slog.Warn("target is out of sync with the source, it will be brought in line", "file", target.VersionedFile, "version", target_version.String(), "source", current_version.String())
+		}
+	}
+	return nil
+}
+
+func toChangelogRequest(file_path string, settings *structpb.Struct, release changelog.Release) *plugin.RequestMessage {
+	var changes []*plugin.ChangelogEntry
+	for _, entry := range release.Entries {
+		change := &plugin.ChangelogEntry{
+			BumpType: entry.Change.BumpType.String(),
+			Message:  entry.Change.Message,
+		}
+		if entry.Commit != nil {
+			change.Commit = entry.Commit.Hash
+			change.PullRequest = int32(entry.Commit.PullRequest())
+		}
+		changes = append(changes, change)
+	}
+
+	return &plugin.RequestMessage{
+		Request: &plugin.RequestMessage_WriteChangelog{
+			WriteChangelog: &plugin.WriteChangelogRequest{
+				FilePath: file_path,
+				Version:  release.Version.String(),
+				Date:     release.Date.Format(time.RFC3339),
+				Changes:  changes,
+				Settings: settings,
+			},
+		},
+	}
+}
+
+// Asks the plugin to write the release to its ecosystem specific changelog file. Returns false without writing
+// anything when the plugin doesn't handle WriteChangelog requests
+func writePluginChangelog(_project project.Project, _config config.Config, _plugin config.Plugin, release changelog.Release) (bool, error) {
+	handler := wasm.NewClient(_plugin, _project.Root, _config)
+	client := plugin.NewVersionGetterSetterServiceClient(handler)
+
+	ctx := context.Background()
+	info, err := handler.Info(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to write changelog: %v", err)
+	}
+	if !info.Supports(plugin.Capability_CAPABILITY_WRITE_CHANGELOG) {
+		return false, nil
+	}
+
+	settings, err := plugin.NewSettings(_plugin.Settings)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := client.Request(ctx, toChangelogRequest(_plugin.ChangelogFile, settings, release))
+	if err != nil {
+		return false, fmt.Errorf("failed to write changelog: %v", err)
+	}
+
+	if resp.Status.Code != 0 {
+		return false, errors.New(resp.Status.Message)
+	}
+
+	println("Updated " + _plugin.ChangelogFile)
+	return true, nil
+}
+
+// Writes the release to the changelog file when a changelog is configured, and to the plugin's changelog when supported
+func writeChangelog(_project project.Project, _config config.Config, next_version version.Version, changes []changeset.Change) error {
+	var changelog_plugins []config.Plugin
+	for _, _plugin := range _config.Plugins() {
+		if _plugin.ChangelogFile != "" {
+			changelog_plugins = append(changelog_plugins, _plugin)
+		}
+	}
+
+	if _config.Changelog == nil && len(changelog_plugins) == 0 {
+		return nil
+	}
+
+	changelog_config := config.Changelog{}
+	if _config.Changelog != nil {
+		changelog_config = *_config.Changelog
+	}
+	release, err := changelog.Build(_project.Root, next_version, time.Now(), changes, changelog_config)
+	if err != nil {
+		return err
+	}
+
+	vocabulary, err := _config.Vocabulary()
+	if err != nil {
+		return err
+	}
+	builtin := release.Render(changelog_config, vocabulary)
+
+	// The changelog files written in the built-in format, so that none is written twice
+	written := map[string]bool{}
+	writeBuiltin := func(filename string) error {
+		if written[_project.Path(filename)] {
+			return nil
+		}
+		if err := changelog.Prepend(_project.Path(filename), builtin); err != nil {
+			return fmt.Errorf("failed to write changelog: %w", err)
+		}
+		written[_project.Path(filename)] = true
+		println("Updated " + filename)
+		return nil
+	}
+
+	if _config.Changelog != nil {
+		if err := writeBuiltin(changelog.Filename(*_config.Changelog)); err != nil {
+			return err
+		}
+	}
+
+	for _, _plugin := range changelog_plugins {
+		handled, err := writePluginChangelog(_project, _config, _plugin, release)
+		if err != nil {
+			return err
+		}
+		if !handled {
+			// Plugins without the request keep working, with their changelog written in the built-in format
+			slog.Debug("plugin doesn't write changelogs, using the built-in format", "plugin", _plugin.Name, "file", _plugin.ChangelogFile)
+			if err := writeBuiltin(_plugin.ChangelogFile); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Aggregates the migration notes of the changes into the migration guide
+func writeMigrationGuide(_project project.Project, _config config.Config, next_version version.Version, changes []changeset.Change) error {
+	guide := changelog.RenderMigrationGuide(next_version, changes)
+	if guide == "" {
+		return nil
+	}
+
+	migration_config := config.Migration{}
+	if _config.Migration != nil {
+		migration_config = *_config.Migration
+	}
+
+	filename := changelog.MigrationFilename(migration_config)
+	if err := changelog.PrependMigrationGuide(_project.Path(filename), guide); err != nil {
+		return fmt.Errorf("failed to write migration guide: %w", err)
+	}
+	println("Updated " + filename)
+	return nil
+}
+
 func Run(cCtx *cli.Context) error {
-	changes, err := changeset.GetChanges()
+	_project, err := project.Resolve(cCtx.String("cwd"), cCtx.String("config"), cCtx.StringSlice("set"))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	changes, err := _project.GetChanges()
 	if err != nil {
 		return cli.Exit(err, 1)
 	}
@@ -61,11 +268,20 @@ func Run(cCtx *cli.Context) error {
 		return nil
 	}
 
-	current_version, err := get_version.GetVersion()
+	current_version, err := get_version.GetVersion(_project)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	_config, err := _project.GetConfig()
 	if err != nil {
 		return cli.Exit(err, 1)
 	}
 
+	if err := checkTargets(_project, _config, current_version); err != nil {
+		return cli.Exit(err, 1)
+	}
+
 	_changeset := changeset.Changeset{
 		CurrentVersion: current_version,
 		Changes:        changes,
@@ -73,7 +289,7 @@ func Run(cCtx *cli.Context) error {
 
 	final_bump_type := _changeset.DetermineFinalBumpType()
 
-	if final_bump_type == version.None {
+	if final_bump_type <= version.None {
 		println(fmt.Sprintf("The version will remain at %s as all changes are not version impacting.", _changeset.CurrentVersion.String()))
 		return nil
 	}
@@ -85,13 +301,22 @@ func Run(cCtx *cli.Context) error {
 		return nil
 	}
 
-	_, err = _changeset.ConsumeChanges()
+	// The version is set first, as it's the step most likely to fail, and nothing else is written unless it succeeds
+	if err := setVersions(_project, _config, next_version); err != nil {
+		return cli.Exit(err, 1)
+	}
 
-	if err != nil {
+	if err := writeChangelog(_project, _config, next_version, changes); err != nil {
 		return cli.Exit(err, 1)
 	}
 
-	if err := setVersion(next_version); err != nil {
+	if err := writeMigrationGuide(_project, _config, next_version, changes); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	_, err = _changeset.ConsumeChanges()
+
+	if err != nil {
 		return cli.Exit(err, 1)
 	}
 
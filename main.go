@@ -1,24 +1,72 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
 
 	"github.com/alex-way/changesets/cmd/add"
+	"github.com/alex-way/changesets/cmd/config_cmd"
 	"github.com/alex-way/changesets/cmd/get_version"
+	"github.com/alex-way/changesets/cmd/init_project"
+	"github.com/alex-way/changesets/cmd/plugin_cmd"
+	"github.com/alex-way/changesets/cmd/preview"
+	"github.com/alex-way/changesets/cmd/validate"
 	"github.com/alex-way/changesets/cmd/version"
+	wasm "github.com/alex-way/changesets/pkg"
 	"github.com/urfave/cli/v2"
 )
 
 var addFlags = []cli.Flag{
-	&cli.StringFlag{Name: "bump-type", Aliases: []string{"t"}},
+	&cli.StringFlag{Name: "bump-type", Aliases: []string{"t"}, Usage: "major, minor, patch, none or one of their configured aliases"},
 	&cli.StringFlag{Name: "message", Aliases: []string{"m"}},
 }
 
+var globalFlags = []cli.Flag{
+	&cli.StringFlag{Name: "cwd", Usage: "run as if started in this directory"},
+	&cli.StringFlag{Name: "config", Aliases: []string{"c"}, Usage: "path to the config file"},
+	&cli.StringSliceFlag{Name: "set", Usage: "override a config field, e.g. --set plugin.url=https://example.com/plugin.wasm"},
+	&cli.BoolFlag{Name: "offline", Usage: "only use plugins from the cache, the same as --set network.offline=true"},
+	&cli.StringFlag{Name: "log-level", Value: "info", EnvVars: []string{"CHANGESET_LOG_LEVEL"}, Usage: "debug, info, warn or error, which includes the logs of plugins"},
+}
+
+// Sets the level of the default logger, which plugin logs are forwarded to
+func setLogLevel(cCtx *cli.Context) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cCtx.String("log-level"))); err != nil {
+		return cli.Exit(fmt.Sprintf("--log-level %q must be one of debug, info, warn or error", cCtx.String("log-level")), 1)
+	}
+	slog.SetLogLoggerLevel(level)
+	return nil
+}
+
+// Applies the global flags which aren't read by the commands themselves
+func before(cCtx *cli.Context) error {
+	if err := setLogLevel(cCtx); err != nil {
+		return err
+	}
+	if cCtx.Bool("offline") {
+		// Applied as an override so that it's part of the resolved config, and shown by config show
+		return cCtx.Set("set", "network.offline=true")
+	}
+	return nil
+}
+
 func main() {
 	app := &cli.App{
-		Name: "changeset",
+		Name:   "changeset",
+		Flags:  globalFlags,
+		Before: before,
+		After: func(cCtx *cli.Context) error {
+			return wasm.Close(cCtx.Context)
+		},
 		Commands: []*cli.Command{
+			{
+				Name:   "init",
+				Flags:  init_project.Flags,
+				Action: init_project.Run,
+			},
 			{
 				Name:   "add",
 				Flags:  addFlags,
@@ -36,6 +84,68 @@ func main() {
 				Name:   "get-version",
 				Action: get_version.Run,
 			},
+			{
+				Name: "config",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "show",
+						Action: config_cmd.Show,
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "origin", Usage: "show which layer set each field"},
+						},
+					},
+					{
+						Name:   "schema",
+						Action: config_cmd.Schema,
+					},
+				},
+			},
+			{
+				Name:  "plugin",
+				Usage: "manage the plugins of the project",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "install",
+						Usage:  "fetch every plugin into the cache and record it in .changeset/plugins.lock",
+						Action: plugin_cmd.Install,
+					},
+					{
+						Name:   "list",
+						Usage:  "list the plugins along with their checksums and whether they're cached",
+						Action: plugin_cmd.List,
+					},
+					{
+						Name:   "update",
+						Usage:  "fetch the latest version of every plugin and pin its new sha256 in the config",
+						Action: plugin_cmd.Update,
+					},
+					{
+						Name:   "verify",
+						Usage:  "re-hash the installed plugins and report any which have been modified",
+						Action: plugin_cmd.Verify,
+					},
+					{
+						Name:      "test",
+						Usage:     "check a plugin conforms to the protocol by running it against a fixture of its versioned file",
+						ArgsUsage: "<plugin>",
+						Action:    plugin_cmd.Test,
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "fixture", Required: true, Usage: "an example versioned file containing a single version"},
+							&cli.StringFlag{Name: "versioned-file", Usage: "the name the fixture is given, defaults to the name of the fixture"},
+							&cli.StringFlag{Name: "expect-version", Usage: "the version the plugin should read from the fixture"},
+							&cli.StringFlag{Name: "settings", Usage: "the plugin settings as a JSON object"},
+						},
+					},
+				},
+			},
+			{
+				Name:   "validate",
+				Action: validate.Run,
+			},
+			{
+				Name:   "preview",
+				Action: preview.Run,
+			},
 		},
 	}
 
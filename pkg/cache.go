@@ -0,0 +1,158 @@
+package wasm
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/lock"
+)
+
+// Where fetched plugins and their compiled code are kept, ~/.cache/changesets
+func CacheDir() (string, error) {
+	home_dir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the home directory: %w", err)
+	}
+	return filepath.Join(home_dir, ".cache", "changesets"), nil
+}
+
+func cachedPath(cache string, sum string) string {
+	return filepath.Join(cache, sum, "plugin.wasm")
+}
+
+// Writes the plugin to the cache, replacing any cached copy which doesn't match its checksum
+func writeCached(cache string, sum string, wmod []byte) error {
+	path := cachedPath(cache, sum)
+	if contents, err := os.ReadFile(path); err == nil {
+		if fmt.Sprintf("%x", sha256.Sum256(contents)) == sum {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove tampered plugin: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("mkdirall: %w", err)
+	}
+	if err := os.WriteFile(path, wmod, 0444); err != nil {
+		return fmt.Errorf("cache wasm: %w", err)
+	}
+	return nil
+}
+
+// Re-hashes the cached plugin with the checksum, returning its path and actual checksum. A plugin whose actual
+// checksum differs has been modified since it was cached. Returns an os.ErrNotExist error when it isn't cached
+func CheckCached(sum string) (string, string, error) {
+	cache, err := CacheDir()
+	if err != nil {
+		return "", "", err
+	}
+	path := cachedPath(cache, sum)
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return path, "", err
+	}
+	return path, fmt.Sprintf("%x", sha256.Sum256(contents)), nil
+}
+
+// A plugin which was installed into the cache
+type Installed struct {
+	SHA256 string
+	// The path of the plugin on disk
+	Path string
+}
+
+// Returns the sha256 the plugin was locked to by `changeset plugin install`, or nothing when it isn't locked
+func lockedChecksum(_plugin config.Plugin, lock_path string) (string, error) {
+	if lock_path == "" {
+		return "", nil
+	}
+	_lock, err := lock.Read(lock_path)
+	if err != nil {
+		return "", err
+	}
+	locked, _ := _lock.Find(_plugin.Name, _plugin.URL)
+	return locked.SHA256, nil
+}
+
+// Fails when the plugin doesn't match its pinned checksum or, when it isn't pinned, the checksum it was locked to
+func checkChecksum(_plugin config.Plugin, lock_path string, sum string) error {
+	if _plugin.SHA256 != "" {
+		if _plugin.SHA256 != sum {
+			return fmt.Errorf("invalid checksum: expected %s, got %s", _plugin.SHA256, sum)
+		}
+		return nil
+	}
+	locked, err := lockedChecksum(_plugin, lock_path)
+	if err != nil {
+		return err
+	}
+	if locked != "" && locked != sum {
+		return fmt.Errorf("invalid checksum: expected %s as locked in %s, got %s. Run `changeset plugin update` if the plugin was meant to change", locked, lock.LOCKFILE_NAME, sum)
+	}
+	return nil
+}
+
+// Fetches the plugin from its URL into the cache. The plugin must match its pinned or locked checksum, unless
+// updating to whatever the URL now serves
+func (r *Runner) Install(ctx context.Context, update bool) (Installed, error) {
+	cache, err := CacheDir()
+	if err != nil {
+		return Installed{}, err
+	}
+
+	wmod, sum, resolved, err := r.fetch(ctx, r.Plugin.URL)
+	var offline_err *OfflineError
+	if errors.As(err, &offline_err) {
+		return Installed{}, fmt.Errorf("plugin %s can't be installed while offline", r.Plugin.Name)
+	} else if err != nil {
+		return Installed{}, err
+	}
+	if !update {
+		if err := checkChecksum(r.Plugin, r.Lock, sum); err != nil {
+			return Installed{}, err
+		}
+	}
+	if err := r.verifySignature(ctx, cache, wmod, sum); err != nil {
+		return Installed{}, err
+	}
+
+	if err := writeCached(cache, sum, wmod); err != nil {
+		return Installed{}, err
+	}
+	slog.Debug("installed plugin", "plugin", r.Plugin.Name, "url", resolved, "sha256", sum)
+	return Installed{SHA256: sum, Path: cachedPath(cache, sum)}, nil
+}
+
+// Resolves the executable and calculates its checksum. Executables are run in place, so nothing is cached
+func (r *ExecRunner) Install(ctx context.Context, update bool) (Installed, error) {
+	path, err := r.path()
+	if err != nil {
+		return Installed{}, err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return Installed{}, fmt.Errorf("os.ReadFile: %s %w", path, err)
+	}
+	sum := fmt.Sprintf("%x", sha256.Sum256(contents))
+	if !update {
+		if err := checkChecksum(r.Plugin, r.Lock, sum); err != nil {
+			return Installed{}, err
+		}
+	}
+	err = verifySignature(r.Plugin, r.Security, contents, func(uri string) ([]byte, error) {
+		return os.ReadFile(r.resolve(strings.TrimPrefix(uri, "file://")))
+	})
+	if err != nil {
+		return Installed{}, err
+	}
+	return Installed{SHA256: sum, Path: path}, nil
+}
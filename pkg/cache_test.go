@@ -0,0 +1,97 @@
+package wasm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/lock"
+)
+
+func TestInstallCachesThePlugin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := newProject(t)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "plugin.wasm"), []byte("not really wasm"), 0644))
+
+	runner := &Runner{Plugin: config.Plugin{Name: "test", URL: "file://plugin.wasm"}, Root: root}
+	installed, err := runner.Install(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(os.Getenv("HOME"), ".cache", "changesets", installed.SHA256, "plugin.wasm"), installed.Path)
+
+	path, actual, err := CheckCached(installed.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, installed.Path, path)
+	assert.Equal(t, installed.SHA256, actual)
+
+	// Tampering with the cached copy is detected, and repaired by installing again
+	require.NoError(t, os.Chmod(path, 0644))
+	require.NoError(t, os.WriteFile(path, []byte("tampered"), 0644))
+	_, actual, err = CheckCached(installed.SHA256)
+	require.NoError(t, err)
+	assert.NotEqual(t, installed.SHA256, actual)
+
+	_, err = runner.Install(context.Background(), false)
+	require.NoError(t, err)
+	_, actual, err = CheckCached(installed.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, installed.SHA256, actual)
+
+	_, _, err = CheckCached("0000")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestInstallChecksThePinUnlessUpdating(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := newProject(t)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "plugin.wasm"), []byte("not really wasm"), 0644))
+
+	runner := &Runner{Plugin: config.Plugin{Name: "test", URL: "file://plugin.wasm", SHA256: "beef"}, Root: root}
+	_, err := runner.Install(context.Background(), false)
+	assert.ErrorContains(t, err, "invalid checksum: expected beef")
+
+	installed, err := runner.Install(context.Background(), true)
+	require.NoError(t, err)
+	assert.NotEqual(t, "beef", installed.SHA256)
+}
+
+func TestUnpinnedPluginsMustMatchTheLock(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := newProject(t)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "plugin.wasm"), []byte("not really wasm"), 0644))
+	lock_path := filepath.Join(root, "plugins.lock")
+	_lock := lock.Lock{Plugins: []lock.Plugin{{Name: "test", URL: "file://plugin.wasm", SHA256: "beef"}}}
+	require.NoError(t, _lock.Write(lock_path))
+
+	runner := &Runner{Plugin: config.Plugin{Name: "test", URL: "file://plugin.wasm"}, Root: root, Lock: lock_path}
+	_, err := runner.Install(context.Background(), false)
+	assert.ErrorContains(t, err, "invalid checksum: expected beef as locked in plugins.lock")
+	sum, err := runner.getChecksum(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "beef", sum)
+
+	// Updating fetches whatever the URL serves, as does a plugin locked under another URL
+	installed, err := runner.Install(context.Background(), true)
+	require.NoError(t, err)
+	_lock.Plugins[0].URL = "file://other.wasm"
+	require.NoError(t, _lock.Write(lock_path))
+	sum, err = runner.getChecksum(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, installed.SHA256, sum)
+}
+
+func TestUnpinnedExecutablesMustMatchTheLock(t *testing.T) {
+	root := newProject(t)
+	runner := newExecRunner(t, root, config.Plugin{})
+	runner.Lock = filepath.Join(root, "plugins.lock")
+	require.NoError(t, lock.Lock{Plugins: []lock.Plugin{{Name: "test", URL: runner.Plugin.URL, SHA256: "beef"}}}.Write(runner.Lock))
+
+	_, err := runner.Install(context.Background(), false)
+	assert.ErrorContains(t, err, "invalid checksum: expected beef as locked in plugins.lock")
+	_, err = getVersionWith(t, runner, nil)
+	assert.ErrorContains(t, err, "invalid checksum: expected beef as locked in plugins.lock")
+}
@@ -0,0 +1,289 @@
+package changelog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alex-way/changesets/pkg/changeset"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/git"
+	"github.com/alex-way/changesets/pkg/version"
+)
+
+const DEFAULT_FILENAME string = "CHANGELOG.md"
+const DEFAULT_COMMIT_URL string = "{repository}/commit/{hash}"
+const DEFAULT_PULL_REQUEST_URL string = "{repository}/pull/{number}"
+const HEADING string = "# Changelog"
+const UNRELEASED_HEADING string = "## Unreleased"
+
+// The order in which groups of changes are rendered
+var groups = []version.BumpType{version.Major, version.Minor, version.Patch, version.None}
+
+type Entry struct {
+	Change changeset.Change
+	// The commit which added the changeset file, nil when it could not be determined
+	Commit *git.Commit
+}
+
+type Release struct {
+	Version version.Version
+	Date    time.Time
+	Entries []Entry
+	// Rendered as a "Contributors" list when not empty
+	Contributors []Contributor
+}
+
+// Returns the path of the changelog file, relative to the project root
+func Filename(cfg config.Changelog) string {
+	if cfg.File == "" {
+		return DEFAULT_FILENAME
+	}
+	return cfg.File
+}
+
+// Creates a release from the given changes, looking up the commit for each change in the local git repository.
+// Changes which don't affect the release are left out
+func NewRelease(next_version version.Version, date time.Time, changes []changeset.Change) Release {
+	release := Release{Version: next_version, Date: date}
+	for _, change := range changes {
+		if !change.AffectsRelease() {
+			continue
+		}
+		entry := Entry{Change: change}
+
+		commit, err := git.FindCommit(change.FilePath)
+		switch {
+		case err == nil:
+			entry.Commit = &commit
+		case errors.Is(err, git.ErrNotRepository), errors.Is(err, git.ErrNoCommit):
+			slog.Debug("no commit found for changeset", "path", change.FilePath, "reason", err)
+		default:
+			slog.Warn("failed to look up commit for changeset", "path", change.FilePath, "error", err)
+		}
+
+		release.Entries = append(release.Entries, entry)
+	}
+	return release
+}
+
+// Creates a release from the given changes, collecting contributors when enabled in the config
+func Build(root string, next_version version.Version, date time.Time, changes []changeset.Change, cfg config.Changelog) (Release, error) {
+	release := NewRelease(next_version, date, changes)
+	if cfg.Contributors != nil {
+		contributors, err := CollectContributors(root, release.Entries, *cfg.Contributors)
+		if err != nil {
+			return Release{}, err
+		}
+		release.Contributors = contributors
+	}
+	return release, nil
+}
+
+// Returns the heading of the group of changes, which is the configured label of the bump type if there is one
+func groupHeading(bump_type version.BumpType, vocabulary version.Vocabulary) string {
+	if label, ok := vocabulary.Labels[bump_type]; ok {
+		return label
+	}
+	switch bump_type {
+	case version.Major:
+		return "Major Changes"
+	case version.Minor:
+		return "Minor Changes"
+	case version.Patch:
+		return "Patch Changes"
+	}
+	return "Other Changes"
+}
+
+func renderLinks(entry Entry, cfg config.Changelog) string {
+	if entry.Commit == nil || cfg.RepositoryURL == "" {
+		return ""
+	}
+	repository := strings.TrimSuffix(cfg.RepositoryURL, "/")
+
+	commit_url := cfg.CommitURL
+	if commit_url == "" {
+		commit_url = DEFAULT_COMMIT_URL
+	}
+	commit_url = strings.NewReplacer("{repository}", repository, "{hash}", entry.Commit.Hash).Replace(commit_url)
+	links := fmt.Sprintf(" ([%s](%s))", entry.Commit.ShortHash(), commit_url)
+
+	if number := entry.Commit.PullRequest(); number != 0 {
+		pull_request_url := cfg.PullRequestURL
+		if pull_request_url == "" {
+			pull_request_url = DEFAULT_PULL_REQUEST_URL
+		}
+		pull_request_url = strings.NewReplacer("{repository}", repository, "{number}", strconv.Itoa(number)).Replace(pull_request_url)
+		links += fmt.Sprintf(" ([#%d](%s))", number, pull_request_url)
+	}
+	return links
+}
+
+func renderEntry(entry Entry, cfg config.Changelog) string {
+	lines := strings.Split(entry.Change.Message, "\n")
+	lines[0] += renderLinks(entry, cfg)
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = "  " + lines[i]
+		}
+	}
+	return "- " + strings.Join(lines, "\n") + "\n"
+}
+
+func renderGroups(builder *strings.Builder, all_entries []Entry, cfg config.Changelog, vocabulary version.Vocabulary) {
+	for _, group := range groups {
+		var entries []Entry
+		for _, entry := range all_entries {
+			if entry.Change.BumpType == group {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		builder.WriteString("\n### " + groupHeading(group, vocabulary) + "\n\n")
+		for _, entry := range entries {
+			builder.WriteString(renderEntry(entry, cfg))
+		}
+	}
+}
+
+// Renders the release as a markdown section
+func (r *Release) Render(cfg config.Changelog, vocabulary version.Vocabulary) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("## %s (%s)\n", r.Version.String(), r.Date.Format("2006-01-02")))
+	renderGroups(&builder, r.Entries, cfg, vocabulary)
+
+	if len(r.Contributors) > 0 {
+		builder.WriteString("\n### Contributors\n\n")
+		for _, contributor := range r.Contributors {
+			name := contributor.Name
+			if name == "" {
+				name = contributor.Email
+			}
+			builder.WriteString("- " + name + "\n")
+		}
+	}
+	return builder.String()
+}
+
+// Renders the pending changes as an "Unreleased" section, or an empty string when there are none.
+// Commit links are left out so that the section doesn't change once the changesets are committed
+func RenderUnreleased(changes []changeset.Change, cfg config.Changelog, vocabulary version.Vocabulary) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	sorted := slices.Clone(changes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].FilePath < sorted[j].FilePath
+	})
+
+	var entries []Entry
+	for _, change := range sorted {
+		if change.AffectsRelease() {
+			entries = append(entries, Entry{Change: change})
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(UNRELEASED_HEADING + "\n")
+	renderGroups(&builder, entries, cfg, vocabulary)
+	return builder.String()
+}
+
+// Splits the body of the changelog into the unreleased section and the remaining sections
+func splitUnreleased(body string) (string, string) {
+	if !strings.HasPrefix(body, UNRELEASED_HEADING+"\n") && body != UNRELEASED_HEADING {
+		return "", body
+	}
+	end := strings.Index(body, "\n## ")
+	if end == -1 {
+		return body, ""
+	}
+	return body[:end], strings.TrimSpace(body[end:])
+}
+
+// Reads the contents of the file below the given top level heading
+func readBody(path string, heading string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(contents)), heading)), nil
+}
+
+// Writes the heading followed by the non-empty sections to the file
+func write(path string, heading string, sections ...string) error {
+	updated := heading + "\n"
+	for _, section := range sections {
+		if section = strings.TrimSpace(section); section != "" {
+			updated += "\n" + section + "\n"
+		}
+	}
+
+	// Avoid touching the file when nothing has changed
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == updated {
+		return nil
+	}
+	return os.WriteFile(path, []byte(updated), 0644)
+}
+
+// Inserts the section at the top of the changelog file, below the main heading, creating the file if needed.
+// Any "Unreleased" section is replaced, as the release consumes all of the pending changes
+func Prepend(path string, section string) error {
+	body, err := readBody(path, HEADING)
+	if err != nil {
+		return err
+	}
+	_, rest := splitUnreleased(body)
+	return write(path, HEADING, section, rest)
+}
+
+// Regenerates the "Unreleased" section of the project's changelog from all pending changes when it's enabled in the
+// config, so that it follows changesets which were edited or removed as well as added. Returns the changelog file and
+// whether it changed
+func UpdateUnreleased(root string, _config config.Config, changes []changeset.Change) (string, bool, error) {
+	if _config.Changelog == nil || !_config.Changelog.Unreleased {
+		return "", false, nil
+	}
+	vocabulary, err := _config.Vocabulary()
+	if err != nil {
+		return "", false, err
+	}
+
+	filename := Filename(*_config.Changelog)
+	path := filepath.Join(root, filename)
+	before, _ := os.ReadFile(path)
+	if err := WriteUnreleased(path, changes, *_config.Changelog, vocabulary); err != nil {
+		return "", false, err
+	}
+	after, _ := os.ReadFile(path)
+	return filename, !bytes.Equal(before, after), nil
+}
+
+// Regenerates the "Unreleased" section of the changelog file from the pending changes
+func WriteUnreleased(path string, changes []changeset.Change, cfg config.Changelog, vocabulary version.Vocabulary) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) && len(changes) == 0 {
+		return nil
+	}
+	body, err := readBody(path, HEADING)
+	if err != nil {
+		return err
+	}
+	_, rest := splitUnreleased(body)
+	return write(path, HEADING, RenderUnreleased(changes, cfg, vocabulary), rest)
+}
@@ -0,0 +1,158 @@
+package changelog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alex-way/changesets/pkg/changeset"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/git"
+	"github.com/alex-way/changesets/pkg/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderGroupsChangesWithLinks(t *testing.T) {
+	release := Release{
+		Version: version.Version{Major: 1, Minor: 2, Patch: 0},
+		Date:    time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Entries: []Entry{
+			{Change: changeset.Change{BumpType: version.Patch, Message: "Fixed a bug"}},
+			{
+				Change: changeset.Change{BumpType: version.Minor, Message: "Added a feature"},
+				Commit: &git.Commit{Hash: "abcdef1234567890", Subject: "Add a feature (#12)"},
+			},
+		},
+	}
+	cfg := config.Changelog{RepositoryURL: "https://github.com/alex-way/changesets/"}
+
+	expected := "## 1.2.0 (2024-06-01)\n" +
+		"\n### Minor Changes\n\n" +
+		"- Added a feature ([abcdef1](https://github.com/alex-way/changesets/commit/abcdef1234567890)) ([#12](https://github.com/alex-way/changesets/pull/12))\n" +
+		"\n### Patch Changes\n\n" +
+		"- Fixed a bug\n"
+	assert.Equal(t, expected, release.Render(cfg, version.Vocabulary{}))
+}
+
+func TestRenderUsesCustomTemplates(t *testing.T) {
+	entry := Entry{
+		Change: changeset.Change{BumpType: version.Patch, Message: "Fixed a bug"},
+		Commit: &git.Commit{Hash: "abcdef1234567890", Subject: "Merge pull request #3 from fork/branch"},
+	}
+	cfg := config.Changelog{
+		RepositoryURL:  "https://gitlab.com/group/project",
+		CommitURL:      "{repository}/-/commit/{hash}",
+		PullRequestURL: "{repository}/-/merge_requests/{number}",
+	}
+
+	expected := "- Fixed a bug ([abcdef1](https://gitlab.com/group/project/-/commit/abcdef1234567890)) ([#3](https://gitlab.com/group/project/-/merge_requests/3))\n"
+	assert.Equal(t, expected, renderEntry(entry, cfg))
+}
+
+func TestRenderUsesConfiguredLabels(t *testing.T) {
+	vocabulary, err := version.NewVocabulary(nil, map[version.BumpType]string{version.Major: "Breaking Changes", version.None: "Chores"})
+	assert.NoError(t, err)
+
+	changes := []changeset.Change{
+		{BumpType: version.Major, Message: "Removed a flag", FilePath: "a.md"},
+		{BumpType: version.Minor, Message: "Added a feature", FilePath: "b.md"},
+		{BumpType: version.None, Message: "Tidied up", FilePath: "c.md"},
+	}
+
+	expected := "## Unreleased\n\n### Breaking Changes\n\n- Removed a flag\n\n### Minor Changes\n\n- Added a feature\n\n### Chores\n\n- Tidied up\n"
+	assert.Equal(t, expected, RenderUnreleased(changes, config.Changelog{}, vocabulary))
+}
+
+func TestPrependKeepsExistingReleases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+
+	assert.NoError(t, Prepend(path, "## 1.0.0 (2024-01-01)\n\n- First\n"))
+	assert.NoError(t, Prepend(path, "## 1.1.0 (2024-02-01)\n\n- Second\n"))
+
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "# Changelog\n\n## 1.1.0 (2024-02-01)\n\n- Second\n\n## 1.0.0 (2024-01-01)\n\n- First\n", string(contents))
+}
+
+func TestWriteUnreleasedIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	assert.NoError(t, Prepend(path, "## 1.0.0 (2024-01-01)\n\n- First\n"))
+
+	changes := []changeset.Change{
+		{BumpType: version.Patch, Message: "Fixed a bug", FilePath: ".changeset/b.md"},
+		{BumpType: version.Minor, Message: "Added a feature", FilePath: ".changeset/a.md"},
+	}
+	assert.NoError(t, WriteUnreleased(path, changes, config.Changelog{}, version.Vocabulary{}))
+	first, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	// Regenerating from the same changes in a different order must not change the file
+	changes[0], changes[1] = changes[1], changes[0]
+	assert.NoError(t, WriteUnreleased(path, changes, config.Changelog{}, version.Vocabulary{}))
+	second, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	expected := "# Changelog\n\n## Unreleased\n" +
+		"\n### Minor Changes\n\n- Added a feature\n" +
+		"\n### Patch Changes\n\n- Fixed a bug\n" +
+		"\n## 1.0.0 (2024-01-01)\n\n- First\n"
+	assert.Equal(t, expected, string(first))
+	assert.Equal(t, string(first), string(second))
+}
+
+func TestPrependReplacesUnreleased(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	changes := []changeset.Change{{BumpType: version.Patch, Message: "Fixed a bug", FilePath: ".changeset/a.md"}}
+	assert.NoError(t, WriteUnreleased(path, changes, config.Changelog{}, version.Vocabulary{}))
+
+	assert.NoError(t, Prepend(path, "## 1.0.1 (2024-01-02)\n\n### Patch Changes\n\n- Fixed a bug\n"))
+
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "# Changelog\n\n## 1.0.1 (2024-01-02)\n\n### Patch Changes\n\n- Fixed a bug\n", string(contents))
+}
+
+func TestWriteUnreleasedWithoutChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	assert.NoError(t, WriteUnreleased(path, nil, config.Changelog{}, version.Vocabulary{}))
+	assert.NoFileExists(t, path)
+}
+
+func TestUpdateUnreleased(t *testing.T) {
+	root := t.TempDir()
+	changes := []changeset.Change{{BumpType: version.Patch, Message: "Fixed a bug", FilePath: ".changeset/a.md"}}
+
+	// Nothing is written unless the section is enabled
+	_, updated, err := UpdateUnreleased(root, config.Config{}, changes)
+	assert.NoError(t, err)
+	assert.False(t, updated)
+	assert.NoFileExists(t, filepath.Join(root, DEFAULT_FILENAME))
+
+	_config := config.Config{Changelog: &config.Changelog{Unreleased: true}}
+	filename, updated, err := UpdateUnreleased(root, _config, changes)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, DEFAULT_FILENAME, filename)
+
+	_, updated, err = UpdateUnreleased(root, _config, changes)
+	assert.NoError(t, err)
+	assert.False(t, updated)
+
+	// Removed changesets are dropped from the section
+	_, updated, err = UpdateUnreleased(root, _config, nil)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	contents, err := os.ReadFile(filepath.Join(root, DEFAULT_FILENAME))
+	assert.NoError(t, err)
+	assert.Equal(t, "# Changelog\n", string(contents))
+}
+
+func TestPrivateChangesAreLeftOut(t *testing.T) {
+	changes := []changeset.Change{
+		{BumpType: version.Major, Message: "Reworked an internal tool", FilePath: "a.md", Private: true, Migration: "Nothing to do"},
+	}
+	assert.Equal(t, "", RenderUnreleased(changes, config.Changelog{}, version.Vocabulary{}))
+	assert.Empty(t, NewRelease(version.Version{Major: 1}, time.Now(), changes).Entries)
+	assert.Equal(t, "", RenderMigrationGuide(version.Version{Major: 1}, changes))
+}
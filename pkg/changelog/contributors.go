@@ -0,0 +1,204 @@
+package changelog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alex-way/changesets/pkg/config"
+)
+
+const DEFAULT_MAILMAP string = ".mailmap"
+
+// Patterns used to detect bots when none are configured
+var DEFAULT_BOT_PATTERNS = []string{`\[bot\]`, `^dependabot`, `^renovate`}
+
+type Contributor struct {
+	Name  string
+	Email string
+}
+
+// Returns the key used to determine whether two contributors are the same person
+func (c Contributor) key() string {
+	if c.Email != "" {
+		return strings.ToLower(c.Email)
+	}
+	return strings.ToLower(c.Name)
+}
+
+// Parses an identity in the form "Name <email>", where both the name and the email are optional
+func ParseContributor(identity string) Contributor {
+	identity = strings.TrimSpace(identity)
+	start := strings.Index(identity, "<")
+	end := strings.LastIndex(identity, ">")
+	if start == -1 || end < start {
+		return Contributor{Name: identity}
+	}
+	return Contributor{
+		Name:  strings.TrimSpace(identity[:start]),
+		Email: strings.TrimSpace(identity[start+1 : end]),
+	}
+}
+
+type mailmapEntry struct {
+	properName  string
+	properEmail string
+	commitName  string
+	commitEmail string
+}
+
+// A parsed git mailmap, see https://git-scm.com/docs/gitmailmap
+type Mailmap []mailmapEntry
+
+var mailmapPattern = regexp.MustCompile(`^([^<]*)<([^>]*)>(?:([^<]*)<([^>]*)>)?`)
+
+func ParseMailmap(contents string) Mailmap {
+	var mailmap Mailmap
+	for _, line := range strings.Split(contents, "\n") {
+		if index := strings.Index(line, "#"); index != -1 {
+			line = line[:index]
+		}
+		match := mailmapPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+
+		entry := mailmapEntry{properName: strings.TrimSpace(match[1])}
+		if match[4] == "" {
+			// "Proper Name <commit@email>"
+			entry.commitEmail = match[2]
+		} else {
+			// "[Proper Name] <proper@email> [Commit Name] <commit@email>"
+			entry.properEmail = match[2]
+			entry.commitName = strings.TrimSpace(match[3])
+			entry.commitEmail = match[4]
+		}
+		mailmap = append(mailmap, entry)
+	}
+	return mailmap
+}
+
+// Returns the canonical identity of the contributor. Entries matching both the name and email take precedence
+func (m Mailmap) Resolve(c Contributor) Contributor {
+	var match *mailmapEntry
+	for i, entry := range m {
+		if !strings.EqualFold(entry.commitEmail, c.Email) {
+			continue
+		}
+		if entry.commitName != "" {
+			if !strings.EqualFold(entry.commitName, c.Name) {
+				continue
+			}
+			match = &m[i]
+			break
+		}
+		if match == nil {
+			match = &m[i]
+		}
+	}
+
+	if match == nil {
+		return c
+	}
+	if match.properName != "" {
+		c.Name = match.properName
+	}
+	if match.properEmail != "" {
+		c.Email = match.properEmail
+	}
+	return c
+}
+
+func readMailmap(root string, path string) (Mailmap, error) {
+	filename := path
+	if filename == "" {
+		filename = DEFAULT_MAILMAP
+	}
+
+	contents, err := os.ReadFile(filepath.Join(root, filename))
+	if err != nil {
+		// A missing default mailmap is fine, but a configured one should exist
+		if os.IsNotExist(err) && path == "" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read mailmap: %w", err)
+	}
+	return ParseMailmap(string(contents)), nil
+}
+
+func compileBotPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		patterns = DEFAULT_BOT_PATTERNS
+	}
+
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bot pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func isBot(c Contributor, patterns []*regexp.Regexp) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(c.Name) || (c.Email != "" && pattern.MatchString(c.Email)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the authors of the entry, taken from the changeset frontmatter or otherwise from the commit which added it
+func entryAuthors(entry Entry) []Contributor {
+	var authors []Contributor
+	for _, author := range entry.Change.Authors {
+		authors = append(authors, ParseContributor(author))
+	}
+	if len(authors) == 0 && entry.Commit != nil {
+		authors = append(authors, Contributor{Name: entry.Commit.AuthorName, Email: entry.Commit.AuthorEmail})
+	}
+	return authors
+}
+
+// Gathers the deduplicated contributors of the release, sorted by name. The mailmap is resolved against the project root
+func CollectContributors(root string, entries []Entry, cfg config.Contributors) ([]Contributor, error) {
+	mailmap, err := readMailmap(root, cfg.Mailmap)
+	if err != nil {
+		return nil, err
+	}
+
+	var bot_patterns []*regexp.Regexp
+	if cfg.ExcludeBots {
+		bot_patterns, err = compileBotPatterns(cfg.BotPatterns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seen := map[string]bool{}
+	var contributors []Contributor
+	for _, entry := range entries {
+		for _, author := range entryAuthors(entry) {
+			author = mailmap.Resolve(author)
+			if author.Name == "" && author.Email == "" {
+				continue
+			}
+			if seen[author.key()] || isBot(author, bot_patterns) {
+				continue
+			}
+			seen[author.key()] = true
+			contributors = append(contributors, author)
+		}
+	}
+
+	sort.SliceStable(contributors, func(i, j int) bool {
+		return strings.ToLower(contributors[i].Name) < strings.ToLower(contributors[j].Name)
+	})
+	return contributors, nil
+}
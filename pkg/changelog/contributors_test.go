@@ -0,0 +1,56 @@
+package changelog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alex-way/changesets/pkg/changeset"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseContributor(t *testing.T) {
+	assert.Equal(t, Contributor{Name: "Jane Doe", Email: "jane@example.com"}, ParseContributor("Jane Doe <jane@example.com>"))
+	assert.Equal(t, Contributor{Name: "jane"}, ParseContributor("jane"))
+}
+
+func TestMailmapResolve(t *testing.T) {
+	mailmap := ParseMailmap(`
+# Comments are ignored
+Jane Doe <jane@example.com>
+Jane Doe <jane@example.com> <jane@old-employer.com>
+John Smith <john@example.com> jsmith <john@laptop.local>
+`)
+
+	assert.Equal(t, Contributor{Name: "Jane Doe", Email: "jane@example.com"}, mailmap.Resolve(Contributor{Name: "jdoe", Email: "jane@example.com"}))
+	assert.Equal(t, Contributor{Name: "Jane Doe", Email: "jane@example.com"}, mailmap.Resolve(Contributor{Name: "Jane", Email: "JANE@old-employer.com"}))
+	assert.Equal(t, Contributor{Name: "John Smith", Email: "john@example.com"}, mailmap.Resolve(Contributor{Name: "jsmith", Email: "john@laptop.local"}))
+	assert.Equal(t, Contributor{Name: "root", Email: "john@laptop.local"}, mailmap.Resolve(Contributor{Name: "root", Email: "john@laptop.local"}))
+}
+
+func TestCollectContributors(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, ".mailmap"), []byte("Jane Doe <jane@example.com> <jane@old-employer.com>\n"), 0644))
+
+	entries := []Entry{
+		{Change: changeset.Change{Authors: []string{"Jane Doe <jane@example.com>"}}},
+		{Change: changeset.Change{}, Commit: &git.Commit{AuthorName: "Jane", AuthorEmail: "jane@old-employer.com"}},
+		{Change: changeset.Change{}, Commit: &git.Commit{AuthorName: "dependabot[bot]", AuthorEmail: "49699333+dependabot[bot]@users.noreply.github.com"}},
+		{Change: changeset.Change{Authors: []string{"Alex Way"}}, Commit: &git.Commit{AuthorName: "Someone Else"}},
+	}
+
+	contributors, err := CollectContributors(root, entries, config.Contributors{ExcludeBots: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []Contributor{{Name: "Alex Way"}, {Name: "Jane Doe", Email: "jane@example.com"}}, contributors)
+
+	contributors, err = CollectContributors(root, entries, config.Contributors{Mailmap: ".mailmap"})
+	assert.NoError(t, err)
+	assert.Len(t, contributors, 3)
+}
+
+func TestCollectContributorsMissingMailmap(t *testing.T) {
+	_, err := CollectContributors(t.TempDir(), nil, config.Contributors{Mailmap: "missing"})
+	assert.Error(t, err)
+}
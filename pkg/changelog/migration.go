@@ -0,0 +1,62 @@
+package changelog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alex-way/changesets/pkg/changeset"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/version"
+)
+
+const DEFAULT_MIGRATION_FILENAME string = "MIGRATING.md"
+const MIGRATION_HEADING string = "# Migration Guide"
+
+// Returns the path of the migration guide file, relative to the project root
+func MigrationFilename(cfg config.Migration) string {
+	if cfg.File == "" {
+		return DEFAULT_MIGRATION_FILENAME
+	}
+	return cfg.File
+}
+
+// Returns the changes which don't include migration notes but should
+func MissingMigrations(changes []changeset.Change, cfg config.Migration) []changeset.Change {
+	var missing []changeset.Change
+	if !cfg.RequireForMajor {
+		return missing
+	}
+	for _, change := range changes {
+		if change.BumpType == version.Major && change.AffectsRelease() && change.Migration == "" {
+			missing = append(missing, change)
+		}
+	}
+	return missing
+}
+
+// Renders the migration notes of all changes under a heading for the new version,
+// or an empty string when none of the changes have migration notes. Changes which don't affect the release are left out
+func RenderMigrationGuide(next_version version.Version, changes []changeset.Change) string {
+	var builder strings.Builder
+	for _, change := range changes {
+		if change.Migration == "" || !change.AffectsRelease() {
+			continue
+		}
+		title := strings.SplitN(change.Message, "\n", 2)[0]
+		builder.WriteString(fmt.Sprintf("\n### %s\n\n%s\n", title, change.Migration))
+	}
+
+	if builder.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("## Migrating to %s\n", next_version.String()) + builder.String()
+}
+
+// Inserts the guide at the top of the migration guide file, below the main heading, creating the file if needed
+func PrependMigrationGuide(path string, guide string) error {
+	body, err := readBody(path, MIGRATION_HEADING)
+	if err != nil {
+		return err
+	}
+	return write(path, MIGRATION_HEADING, guide, body)
+}
@@ -0,0 +1,46 @@
+package changelog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alex-way/changesets/pkg/changeset"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingMigrations(t *testing.T) {
+	changes := []changeset.Change{
+		{BumpType: version.Major, Message: "Removed the old API", Migration: "Use the new API."},
+		{BumpType: version.Major, Message: "Renamed a flag", FilePath: ".changeset/renamed.md"},
+		{BumpType: version.Minor, Message: "Added a feature"},
+	}
+
+	assert.Empty(t, MissingMigrations(changes, config.Migration{}))
+
+	missing := MissingMigrations(changes, config.Migration{RequireForMajor: true})
+	assert.Len(t, missing, 1)
+	assert.Equal(t, ".changeset/renamed.md", missing[0].FilePath)
+}
+
+func TestPrependMigrationGuide(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "MIGRATING.md")
+	changes := []changeset.Change{
+		{BumpType: version.Major, Message: "Removed the old API", Migration: "Use the new API."},
+		{BumpType: version.Minor, Message: "Added a feature"},
+	}
+
+	assert.Equal(t, "", RenderMigrationGuide(version.Version{Major: 1}, changes[1:]))
+
+	assert.NoError(t, PrependMigrationGuide(path, RenderMigrationGuide(version.Version{Major: 1}, changes)))
+	assert.NoError(t, PrependMigrationGuide(path, RenderMigrationGuide(version.Version{Major: 2}, changes)))
+
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	expected := "# Migration Guide\n" +
+		"\n## Migrating to 2.0.0\n\n### Removed the old API\n\nUse the new API.\n" +
+		"\n## Migrating to 1.0.0\n\n### Removed the old API\n\nUse the new API.\n"
+	assert.Equal(t, expected, string(contents))
+}
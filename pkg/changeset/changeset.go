@@ -3,7 +3,9 @@ package changeset
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -20,11 +22,30 @@ var names = []string{"hello", "world", "dog", "arnold", "cat", "kitten", "puppy"
 const CHANGE_NAME_PARTS int8 = 3
 const CHANGESET_DIRECTORY string = ".changeset"
 const CHANGESET_FILE_KEY string = "changeset/type"
+const CHANGESET_AUTHORS_KEY string = "authors"
+const CHANGESET_PACKAGES_KEY string = "packages"
+const MIGRATION_HEADING string = "## Migration"
 
 type Change struct {
 	BumpType version.BumpType
 	Message  string
 	FilePath string
+	// Authors listed in the frontmatter, e.g. "Jane Doe <jane@example.com>"
+	Authors []string
+	// The contents of the "## Migration" section of the body, if present
+	Migration string
+	// Packages listed in the frontmatter, empty when the change applies to the whole project
+	Packages []string
+	// Whether all of the packages are private, in which case the change is consumed without affecting the release
+	Private bool
+	// Whether all of the packages are ignored, in which case the change is consumed without affecting the release
+	Ignored bool
+}
+
+// Whether the change bumps the version and appears in the changelog, which changes only referencing private or
+// ignored packages don't
+func (c Change) AffectsRelease() bool {
+	return !c.Private && !c.Ignored
 }
 
 type Changeset struct {
@@ -46,18 +67,19 @@ func generateChangeName() string {
 	return strings.Join(parts[:], "-")
 }
 
-// Creates a new change set file and returns the filepath
-func CreateChangeFile(bump_type version.BumpType, message string) (string, error) {
+// Creates a new change set file within the project root and returns the filepath
+func CreateChangeFile(root string, bump_type version.BumpType, message string) (string, error) {
 	filename := generateChangeName()
+	directory := filepath.Join(root, CHANGESET_DIRECTORY)
 
-	if _, err := os.Stat(CHANGESET_DIRECTORY); os.IsNotExist(err) {
-		err := os.Mkdir(CHANGESET_DIRECTORY, 0755)
+	if _, err := os.Stat(directory); os.IsNotExist(err) {
+		err := os.Mkdir(directory, 0755)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	filepath := filepath.Join(CHANGESET_DIRECTORY, filename+".md")
+	filepath := filepath.Join(directory, filename+".md")
 
 	file, err := os.Create(filepath)
 	if err != nil {
@@ -77,6 +99,9 @@ func CreateChangeFile(bump_type version.BumpType, message string) (string, error
 func (cs *Changeset) DetermineFinalBumpType() version.BumpType {
 	var highest_version_type version.BumpType = version.Undetermined
 	for _, change := range cs.Changes {
+		if !change.AffectsRelease() {
+			continue
+		}
 		if change.BumpType >= highest_version_type {
 			highest_version_type = change.BumpType
 		}
@@ -84,8 +109,24 @@ func (cs *Changeset) DetermineFinalBumpType() version.BumpType {
 	return highest_version_type
 }
 
-func GetChanges() ([]Change, error) {
-	files, err := filepath.Glob(CHANGESET_DIRECTORY + "/*.md")
+// Controls how changeset files are read
+type Options struct {
+	// The words accepted for the changeset type, which are the bump type names when empty
+	Vocabulary version.Vocabulary
+	// Glob patterns of files within the changeset directory which aren't changesets, in addition to DEFAULT_IGNORE
+	Ignore []string
+	// Glob patterns of packages which are never versioned. Changesets which only reference them are skipped
+	IgnoredPackages []string
+	// Glob patterns of packages whose changes are consumed without affecting the version or changelog
+	PrivatePackages []string
+}
+
+// Files within the changeset directory which are never read as changesets
+var DEFAULT_IGNORE = []string{"README.md"}
+
+// Reads all pending changes from the changeset directory within the project root
+func GetChanges(root string, opts Options) ([]Change, error) {
+	files, err := filepath.Glob(filepath.Join(root, CHANGESET_DIRECTORY, "*.md"))
 
 	if err != nil {
 		panic(err)
@@ -94,6 +135,14 @@ func GetChanges() ([]Change, error) {
 	var changes []Change
 
 	for _, filepath := range files {
+		ignored, err := isIgnored(filepath, opts.Ignore)
+		if err != nil {
+			return nil, err
+		}
+		if ignored {
+			continue
+		}
+
 		file, err := os.Open(filepath)
 		if err != nil {
 			panic(err)
@@ -116,19 +165,111 @@ func GetChanges() ([]Change, error) {
 			panic(err)
 		}
 		metaData := meta.Get(context)
-		ver := metaData[CHANGESET_FILE_KEY].(string)
+		ver, _ := metaData[CHANGESET_FILE_KEY].(string)
 		if ver == "" {
-			return nil, errors.New("changeset file does not have a type")
+			return nil, fmt.Errorf("%s: changeset file does not have a type", filepath)
 		}
-		parsed_bump_type, err := version.ParseBumpType(ver)
+		parsed_bump_type, err := opts.Vocabulary.Parse(ver)
 		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath, err)
+		}
+		message, migration := parseBody(string(contents))
+		change := Change{
+			BumpType:  parsed_bump_type,
+			Message:   message,
+			FilePath:  filepath,
+			Authors:   parseAuthors(metaData[CHANGESET_AUTHORS_KEY]),
+			Migration: migration,
+			Packages:  parseStrings(metaData[CHANGESET_PACKAGES_KEY]),
+		}
+
+		if err := applyPackages(&change, opts); err != nil {
 			return nil, err
 		}
-		changes = append(changes, Change{BumpType: parsed_bump_type, Message: "thing", FilePath: filepath})
+		if change.Ignored {
+			slog.Info("changeset only references ignored packages, so is consumed without affecting the release", "path", filepath, "packages", change.Packages)
+		}
+		changes = append(changes, change)
 	}
 	return changes, nil
 }
 
+// Parses the authors frontmatter value, which may be either a single string or a list of strings
+func parseAuthors(value interface{}) []string {
+	return parseStrings(value)
+}
+
+// Parses a frontmatter value which may be either a single string or a list of strings
+func parseStrings(value interface{}) []string {
+	var values []string
+	switch value := value.(type) {
+	case string:
+		if value != "" {
+			values = append(values, value)
+		}
+	case []interface{}:
+		for _, item := range value {
+			if item, ok := item.(string); ok && item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// Returns the marker opening a fenced code block, e.g. ``` or ~~~~, or nothing when the line doesn't open one
+func fenceMarker(line string) string {
+	for _, char := range []string{"`", "~"} {
+		marker := line[:len(line)-len(strings.TrimLeft(line, char))]
+		if len(marker) >= 3 {
+			return marker
+		}
+	}
+	return ""
+}
+
+// Extracts the message and migration notes from the body of a changeset file, stripping the frontmatter and leading heading
+func parseBody(contents string) (string, string) {
+	body := strings.TrimSpace(contents)
+	if strings.HasPrefix(body, "---") {
+		parts := strings.SplitN(body, "\n---", 2)
+		if len(parts) == 2 {
+			body = parts[1]
+		}
+	}
+
+	var message, migration []string
+	in_migration := false
+	// The marker of the fenced code block the line is in, within which lines such as shell comments aren't headings
+	fence := ""
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+		case fenceMarker(trimmed) != "":
+			fence = fenceMarker(trimmed)
+		case strings.EqualFold(trimmed, MIGRATION_HEADING):
+			in_migration = true
+			continue
+		case in_migration && (strings.HasPrefix(trimmed, "## ") || strings.HasPrefix(trimmed, "# ")):
+			in_migration = false
+		}
+
+		if in_migration {
+			migration = append(migration, line)
+		} else {
+			message = append(message, line)
+		}
+	}
+
+	parsed_message := strings.TrimSpace(strings.Join(message, "\n"))
+	parsed_message = strings.TrimSpace(strings.TrimPrefix(parsed_message, "# "))
+	return parsed_message, strings.TrimSpace(strings.Join(migration, "\n"))
+}
+
 func (cs *Changeset) DetermineNextVersion() version.Version {
 	next_version := cs.CurrentVersion
 	next_version.Bump(cs.DetermineFinalBumpType())
@@ -1,6 +1,8 @@
 package changeset
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -59,3 +61,107 @@ func TestDetermineFinalBumpType(t *testing.T) {
 	}
 	assert.Equal(t, version.Undetermined, changeset.DetermineFinalBumpType())
 }
+
+func TestParseBody(t *testing.T) {
+	contents := "---\nchangeset/type: minor\n---\n\n# Added a new feature\n"
+	message, migration := parseBody(contents)
+	assert.Equal(t, "Added a new feature", message)
+	assert.Equal(t, "", migration)
+
+	contents = "---\nchangeset/type: patch\n---\n\n# Fixed a bug\n\nWith some more detail.\n"
+	message, _ = parseBody(contents)
+	assert.Equal(t, "Fixed a bug\n\nWith some more detail.", message)
+}
+
+func TestParseBodyWithMigration(t *testing.T) {
+	contents := "---\nchangeset/type: major\n---\n\n# Removed the old API\n\n## Migration\n\nUse the new API instead:\n\n```go\nnew.API()\n```\n"
+	message, migration := parseBody(contents)
+	assert.Equal(t, "Removed the old API", message)
+	assert.Equal(t, "Use the new API instead:\n\n```go\nnew.API()\n```", migration)
+
+	contents = "# Removed the old API\n\n## Migration\n\nUse the new API.\n\n## Notes\n\nSome notes.\n"
+	message, migration = parseBody(contents)
+	assert.Equal(t, "Removed the old API\n\n## Notes\n\nSome notes.", message)
+	assert.Equal(t, "Use the new API.", migration)
+}
+
+func TestParseBodyIgnoresHeadingsInCodeBlocks(t *testing.T) {
+	contents := "# Moved the config\n\n## Migration\n\nMove the config:\n\n```bash\n# run this from the project root\nmv changeset.json .changeset/config.json\n```\n\n~~~\n## not a heading\n~~~\n\n## Notes\n\nSome notes.\n"
+	message, migration := parseBody(contents)
+	assert.Equal(t, "Moved the config\n\n## Notes\n\nSome notes.", message)
+	assert.Equal(t, "Move the config:\n\n```bash\n# run this from the project root\nmv changeset.json .changeset/config.json\n```\n\n~~~\n## not a heading\n~~~", migration)
+
+	// A migration heading within a code block of the message doesn't start the migration notes
+	contents = "# Documented the migration heading\n\n````md\n## Migration\n````\n"
+	message, migration = parseBody(contents)
+	assert.Equal(t, "Documented the migration heading\n\n````md\n## Migration\n````", message)
+	assert.Equal(t, "", migration)
+}
+
+func TestParseAuthors(t *testing.T) {
+	assert.Equal(t, []string{"Jane Doe <jane@example.com>"}, parseAuthors("Jane Doe <jane@example.com>"))
+	assert.Equal(t, []string{"jane", "john"}, parseAuthors([]interface{}{"jane", "john"}))
+	assert.Nil(t, parseAuthors(nil))
+}
+
+func TestGetChangesAcceptsAliases(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, CHANGESET_DIRECTORY), 0755))
+	path := filepath.Join(root, CHANGESET_DIRECTORY, "happy-robot-whale.md")
+	assert.NoError(t, os.WriteFile(path, []byte("---\nchangeset/type: breaking\n---\n\n# Removed a flag\n"), 0644))
+
+	_, err := GetChanges(root, Options{})
+	assert.ErrorContains(t, err, `invalid bump type "breaking"`)
+
+	vocabulary, err := version.NewVocabulary(map[version.BumpType][]string{version.Major: {"breaking"}}, nil)
+	assert.NoError(t, err)
+	changes, err := GetChanges(root, Options{Vocabulary: vocabulary})
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{BumpType: version.Major, Message: "Removed a flag", FilePath: path}}, changes)
+}
+
+func writeChangeset(t *testing.T, root string, name string, contents string) string {
+	path := filepath.Join(root, CHANGESET_DIRECTORY, name)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func TestGetChangesSkipsIgnoredFiles(t *testing.T) {
+	root := t.TempDir()
+	writeChangeset(t, root, "README.md", "# Changesets\n\nThis directory holds pending changes.\n")
+	writeChangeset(t, root, "TEMPLATE.md", "---\nchangeset/type: ???\n---\n")
+	path := writeChangeset(t, root, "happy-robot-whale.md", "---\nchangeset/type: patch\n---\n\n# Fixed a bug\n")
+
+	changes, err := GetChanges(root, Options{Ignore: []string{"TEMPLATE*"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{BumpType: version.Patch, Message: "Fixed a bug", FilePath: path}}, changes)
+
+	writeChangeset(t, root, "notes.md", "# Notes\n")
+	_, err = GetChanges(root, Options{Ignore: []string{"TEMPLATE*"}})
+	assert.ErrorContains(t, err, "notes.md: changeset file does not have a type")
+}
+
+func TestGetChangesHonoursPackages(t *testing.T) {
+	root := t.TempDir()
+	opts := Options{IgnoredPackages: []string{"tools/*"}, PrivatePackages: []string{"internal"}}
+
+	ignored := writeChangeset(t, root, "a.md", "---\nchangeset/type: major\npackages: tools/linter\n---\n\n# Rewrote the linter\n")
+	private := writeChangeset(t, root, "b.md", "---\nchangeset/type: minor\npackages: [internal]\n---\n\n# Added an internal helper\n")
+	public := writeChangeset(t, root, "c.md", "---\nchangeset/type: patch\npackages: [app, internal]\n---\n\n# Fixed the app\n")
+
+	changes, err := GetChanges(root, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{BumpType: version.Major, Message: "Rewrote the linter", FilePath: ignored, Packages: []string{"tools/linter"}, Ignored: true},
+		{BumpType: version.Minor, Message: "Added an internal helper", FilePath: private, Packages: []string{"internal"}, Private: true},
+		{BumpType: version.Patch, Message: "Fixed the app", FilePath: public, Packages: []string{"app", "internal"}},
+	}, changes)
+
+	_changeset := Changeset{Changes: changes}
+	assert.Equal(t, version.Patch, _changeset.DetermineFinalBumpType())
+
+	writeChangeset(t, root, "d.md", "---\nchangeset/type: patch\npackages: [app, tools/linter]\n---\n\n# Mixed\n")
+	_, err = GetChanges(root, opts)
+	assert.ErrorContains(t, err, "references the ignored package(s) tools/linter alongside app")
+}
@@ -0,0 +1,65 @@
+package changeset
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Returns the first of the glob patterns matching the name, or an empty string if none do
+func matchAny(patterns []string, name string) (string, error) {
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return pattern, nil
+		}
+	}
+	return "", nil
+}
+
+// Whether the file within the changeset directory should not be read as a changeset
+func isIgnored(file string, patterns []string) (bool, error) {
+	pattern, err := matchAny(slices.Concat(DEFAULT_IGNORE, patterns), filepath.Base(file))
+	return pattern != "", err
+}
+
+// Marks the change as private or ignored when all of its packages are. Mixing ignored and other packages in one
+// changeset is an error
+func applyPackages(change *Change, opts Options) error {
+	if len(change.Packages) == 0 {
+		return nil
+	}
+
+	var ignored, private, versioned []string
+	for _, name := range change.Packages {
+		if pattern, err := matchAny(opts.IgnoredPackages, name); err != nil {
+			return err
+		} else if pattern != "" {
+			ignored = append(ignored, name)
+			continue
+		}
+		if pattern, err := matchAny(opts.PrivatePackages, name); err != nil {
+			return err
+		} else if pattern != "" {
+			private = append(private, name)
+			continue
+		}
+		versioned = append(versioned, name)
+	}
+
+	if len(ignored) == len(change.Packages) {
+		change.Ignored = true
+		return nil
+	}
+	if len(ignored) > 0 {
+		return fmt.Errorf("%s: references the ignored package(s) %s alongside %s, move them into a separate changeset", change.FilePath, strings.Join(ignored, ", "), strings.Join(append(private, versioned...), ", "))
+	}
+
+	change.Private = len(versioned) == 0
+	return nil
+}
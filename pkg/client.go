@@ -0,0 +1,73 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"google.golang.org/grpc"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/lock"
+	"github.com/alex-way/changesets/pkg/plugin"
+)
+
+// A connection to a plugin, however it's run
+type Client interface {
+	grpc.ClientConnInterface
+	// Returns the sha256 of the plugin, ignoring any checksum set in the config
+	Checksum(ctx context.Context) (string, error)
+	// Returns what the plugin reported about itself when it was loaded
+	Info(ctx context.Context) (*plugin.GetInfoResponse, error)
+	// Fetches the plugin ahead of running it, checking it against its pinned checksum unless updating
+	Install(ctx context.Context, update bool) (Installed, error)
+}
+
+// Returns the client for the plugin, which runs it as an executable when it's configured to be one and as a WASM
+// module otherwise. The config decides whether the plugin must be pinned and signed, and how it's downloaded. Plugins
+// which aren't pinned must match the checksum they were locked to in the project's lockfile
+func NewClient(_plugin config.Plugin, root string, _config config.Config) Client {
+	lock_path := filepath.Join(root, config.CHANGESET_DIRECTORY, lock.LOCKFILE_NAME)
+	if _plugin.IsExec() {
+		return &ExecRunner{Plugin: _plugin, Root: root, Security: _config.Security, Lock: lock_path}
+	}
+	return &Runner{Plugin: _plugin, Root: root, Security: _config.Security, Network: _config.Network, Lock: lock_path}
+}
+
+// Asks the plugin which protocol version and requests it supports, failing when the host can't speak its protocol.
+// Plugins which don't handle the GetInfo request are assumed to speak protocol 1
+func handshake(name string, invoke func(req *plugin.RequestMessage, resp *plugin.Response) error) (*plugin.GetInfoResponse, error) {
+	req := &plugin.RequestMessage{Request: &plugin.RequestMessage_GetInfo{
+		GetInfo: &plugin.GetInfoRequest{ProtocolVersion: plugin.PROTOCOL_VERSION},
+	}}
+	var resp plugin.Response
+	err := invoke(req, &resp)
+
+	var limit_err *LimitError
+	var access_err *AccessError
+	if errors.As(err, &limit_err) || errors.As(err, &access_err) {
+		return nil, err
+	}
+	info := resp.GetGetInfo()
+	if err != nil || info == nil {
+		slog.Debug("plugin doesn't support GetInfo, assuming protocol version 1", "plugin", name, "error", err)
+		info = plugin.LegacyInfo()
+	}
+	if err := info.CheckProtocol(name); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Returns an error when the plugin didn't report being able to handle the request
+func checkCapability(name string, info *plugin.GetInfoResponse, req *plugin.RequestMessage) error {
+	if req == nil {
+		return nil
+	}
+	if capability := plugin.CapabilityOf(req); !info.Supports(capability) {
+		return fmt.Errorf("plugin %s does not support %s requests", name, plugin.DescribeCapability(capability))
+	}
+	return nil
+}
@@ -1,46 +1,409 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/alex-way/changesets/pkg/minisign"
+	"github.com/alex-way/changesets/pkg/version"
 )
 
 const CHANGESET_DIRECTORY string = ".changeset"
 const CONFIG_FILENAME string = "config.json"
 
+var ErrNotFound = errors.New("config file not found")
+
+type Limits struct {
+	// The maximum duration of a single request, e.g. 30s
+	Timeout string `json:"timeout" toml:"timeout" yaml:"timeout"`
+	// The maximum memory of the plugin in 64KiB WebAssembly pages
+	MemoryPages uint32 `json:"memoryPages" toml:"memoryPages" yaml:"memoryPages"`
+	// The maximum number of bytes the plugin may write to each of stdout and stderr
+	OutputBytes int `json:"outputBytes" toml:"outputBytes" yaml:"outputBytes"`
+}
+
+// The ways a plugin can be run
+const PLUGIN_TYPE_WASM string = "wasm"
+const PLUGIN_TYPE_EXEC string = "exec"
+
 type Plugin struct {
-	Name          string `json:"name"`
-	URL           string `json:"url"`
-	SHA256        string `json:"sha256"`
-	VersionedFile string `json:"versionedFile"`
+	Name string `json:"name" toml:"name" yaml:"name"`
+	URL  string `json:"url" toml:"url" yaml:"url"`
+	// How the plugin is run, either wasm (the default) or exec. Plugins with an exec:// URL are always executables
+	Type          string `json:"type,omitempty" toml:"type,omitempty" yaml:"type,omitempty"`
+	SHA256        string `json:"sha256" toml:"sha256" yaml:"sha256"`
+	VersionedFile string `json:"versionedFile" toml:"versionedFile" yaml:"versionedFile"`
+	// The changelog file written by the plugin. Only set this for plugins which support the WriteChangelog request
+	ChangelogFile string `json:"changelogFile,omitempty" toml:"changelogFile,omitempty" yaml:"changelogFile,omitempty"`
+	// Free-form options passed through to the plugin with every request, e.g. the table of pyproject.toml to update
+	Settings map[string]interface{} `json:"settings,omitempty" toml:"settings,omitempty" yaml:"settings,omitempty"`
+	// The minisign signature of the plugin, as a URL or a path relative to the project root. Defaults to the URL
+	// of the plugin with .minisig appended
+	Signature string `json:"signature,omitempty" toml:"signature,omitempty" yaml:"signature,omitempty"`
+	// Limits on the resources the plugin may use, each of which has a default when unset
+	Limits *Limits `json:"limits,omitempty" toml:"limits,omitempty" yaml:"limits,omitempty"`
+	// Whether the version is read from this target. Only used within targets, where it defaults to the first one
+	Source bool `json:"source,omitempty" toml:"source,omitempty" yaml:"source,omitempty"`
+}
+
+type Security struct {
+	// The minisign public keys trusted to sign plugins, as the base64 encoded line of each minisign.pub file.
+	// Once set, plugins which aren't signed by one of these keys are refused
+	TrustedKeys []string `json:"trustedKeys,omitempty" toml:"trustedKeys,omitempty" yaml:"trustedKeys,omitempty"`
+	// Whether plugins must be pinned with a sha256 and signed, rather than only warning when they aren't pinned
+	Strict bool `json:"strict,omitempty" toml:"strict,omitempty" yaml:"strict,omitempty"`
+}
+
+// How plugins are downloaded
+type Network struct {
+	// Whether plugins are only loaded from the cache, failing rather than downloading them
+	Offline bool `json:"offline,omitempty" toml:"offline,omitempty" yaml:"offline,omitempty"`
+	// Plugin URLs starting with a key are downloaded from its value instead, e.g. from https://github.com/ to
+	// https://artifacts.example.com/github/. The longest matching key is used
+	Mirrors map[string]string `json:"mirrors,omitempty" toml:"mirrors,omitempty" yaml:"mirrors,omitempty"`
+	// How many times a failed download is retried, defaults to 3
+	Retries int `json:"retries,omitempty" toml:"retries,omitempty" yaml:"retries,omitempty"`
+}
+
+const DEFAULT_RETRIES int = 3
+
+type Contributors struct {
+	// Path to a git mailmap file used to merge the identities of contributors, defaults to .mailmap
+	Mailmap string `json:"mailmap" toml:"mailmap" yaml:"mailmap"`
+	// Whether contributors matching BotPatterns are left out of the list
+	ExcludeBots bool `json:"excludeBots" toml:"excludeBots" yaml:"excludeBots"`
+	// Regular expressions matched against the name and email of each contributor
+	BotPatterns []string `json:"botPatterns" toml:"botPatterns" yaml:"botPatterns"`
+}
+
+type Changelog struct {
+	// The file the changelog is written to, defaults to CHANGELOG.md
+	File string `json:"file" toml:"file" yaml:"file"`
+	// The base URL of the repository, e.g. https://github.com/alex-way/changesets
+	RepositoryURL string `json:"repositoryUrl" toml:"repositoryUrl" yaml:"repositoryUrl"`
+	// Template for commit links. Supports the {repository} and {hash} placeholders
+	CommitURL string `json:"commitUrl" toml:"commitUrl" yaml:"commitUrl"`
+	// Template for pull request links. Supports the {repository} and {number} placeholders
+	PullRequestURL string `json:"pullRequestUrl" toml:"pullRequestUrl" yaml:"pullRequestUrl"`
+	// Keeps an "Unreleased" section listing all pending changesets at the top of the changelog
+	Unreleased bool `json:"unreleased" toml:"unreleased" yaml:"unreleased"`
+	// Lists the contributors of each release when set
+	Contributors *Contributors `json:"contributors,omitempty" toml:"contributors,omitempty" yaml:"contributors,omitempty"`
+}
+
+type Migration struct {
+	// The file migration guides are written to, defaults to MIGRATING.md
+	File string `json:"file" toml:"file" yaml:"file"`
+	// Whether major changesets must include a "## Migration" section to pass validation
+	RequireForMajor bool `json:"requireForMajor" toml:"requireForMajor" yaml:"requireForMajor"`
+}
+
+type Bump struct {
+	// Words accepted in place of the name of the bump type, e.g. breaking for major
+	Aliases []string `json:"aliases" toml:"aliases" yaml:"aliases"`
+	// Shown when choosing the bump type and used as its changelog heading
+	Label string `json:"label" toml:"label" yaml:"label"`
+}
+
+// The vocabulary of each bump type
+type Bumps struct {
+	Major *Bump `json:"major,omitempty" toml:"major,omitempty" yaml:"major,omitempty"`
+	Minor *Bump `json:"minor,omitempty" toml:"minor,omitempty" yaml:"minor,omitempty"`
+	Patch *Bump `json:"patch,omitempty" toml:"patch,omitempty" yaml:"patch,omitempty"`
+	None  *Bump `json:"none,omitempty" toml:"none,omitempty" yaml:"none,omitempty"`
+}
+
+type Packages struct {
+	// Glob patterns of packages which are never versioned. Changesets which only reference them are skipped
+	Ignore []string `json:"ignore" toml:"ignore" yaml:"ignore"`
+	// Glob patterns of packages whose changes are consumed without affecting the version or changelog
+	Private []string `json:"private" toml:"private" yaml:"private"`
 }
 
 type Config struct {
-	Plugin Plugin `json:"plugin"`
+	// The JSON Schema the file is written against, for editors. It has no effect on the config
+	Schema string `json:"$schema,omitempty" toml:"$schema,omitempty" yaml:"$schema,omitempty"`
+	// A base config this config is merged over, as a path relative to this file or a file:// URI
+	Extends string `json:"extends,omitempty" toml:"extends,omitempty" yaml:"extends,omitempty"`
+	Plugin  Plugin `json:"plugin" toml:"plugin" yaml:"plugin"`
+	// Several versioned files kept in sync, each with its own plugin. Replaces Plugin when set
+	Targets   []Plugin   `json:"targets,omitempty" toml:"targets,omitempty" yaml:"targets,omitempty"`
+	Changelog *Changelog `json:"changelog,omitempty" toml:"changelog,omitempty" yaml:"changelog,omitempty"`
+	Migration *Migration `json:"migration,omitempty" toml:"migration,omitempty" yaml:"migration,omitempty"`
+	Bumps     *Bumps     `json:"bumps,omitempty" toml:"bumps,omitempty" yaml:"bumps,omitempty"`
+	// Glob patterns of files within the changeset directory which aren't changesets. README.md is always ignored
+	Ignore   []string  `json:"ignore,omitempty" toml:"ignore,omitempty" yaml:"ignore,omitempty"`
+	Packages *Packages `json:"packages,omitempty" toml:"packages,omitempty" yaml:"packages,omitempty"`
+	Security *Security `json:"security,omitempty" toml:"security,omitempty" yaml:"security,omitempty"`
+	Network  *Network  `json:"network,omitempty" toml:"network,omitempty" yaml:"network,omitempty"`
+}
+
+// Whether the plugin is a local executable rather than a WASM module
+func (p Plugin) IsExec() bool {
+	return p.Type == PLUGIN_TYPE_EXEC || strings.HasPrefix(p.URL, "exec://")
 }
 
-func GetConfig() (Config, error) {
-	filepath := filepath.Join(CHANGESET_DIRECTORY, CONFIG_FILENAME)
-	if _, err := os.Stat(filepath); os.IsNotExist(err) {
-		return Config{}, errors.New("config file not found")
+// Returns the trusted keys, or none when signatures aren't verified
+func (s *Security) PublicKeys() ([]minisign.PublicKey, error) {
+	if s == nil {
+		return nil, nil
 	}
-	file, err := os.Open(filepath)
-	if err != nil {
-		return Config{}, err
+	var keys []minisign.PublicKey
+	for _, trusted := range s.TrustedKeys {
+		key, err := minisign.ParsePublicKey(trusted)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Whether plugins must be pinned and signed
+func (s *Security) IsStrict() bool {
+	return s != nil && s.Strict
+}
+
+func (n *Network) IsOffline() bool {
+	return n != nil && n.Offline
+}
+
+// Returns the number of times a failed download is retried
+func (n *Network) RetryCount() int {
+	if n == nil || n.Retries == 0 {
+		return DEFAULT_RETRIES
+	}
+	return n.Retries
+}
+
+// Returns the URL the plugin is downloaded from, which is on the mirror matching the longest prefix of the URL
+func (n *Network) Mirror(url string) string {
+	if n == nil {
+		return url
+	}
+	prefix := ""
+	for from := range n.Mirrors {
+		if strings.HasPrefix(url, from) && len(from) > len(prefix) {
+			prefix = from
+		}
+	}
+	if prefix == "" {
+		return url
+	}
+	return n.Mirrors[prefix] + strings.TrimPrefix(url, prefix)
+}
+
+// Returns the plugins of every versioned file, which is either the targets or the single plugin
+func (c Config) Plugins() []Plugin {
+	if len(c.Targets) > 0 {
+		return c.Targets
+	}
+	return []Plugin{c.Plugin}
+}
+
+// Returns the plugin the current version is read from
+func (c Config) Source() Plugin {
+	plugins := c.Plugins()
+	for _, plugin := range plugins {
+		if plugin.Source {
+			return plugin
+		}
+	}
+	return plugins[0]
+}
+
+// Returns the words and labels used for bump types, which are the defaults unless configured
+func (c Config) Vocabulary() (version.Vocabulary, error) {
+	if c.Bumps == nil {
+		return version.Vocabulary{}, nil
 	}
-	defer file.Close()
-	contents, err := io.ReadAll(file)
+
+	aliases := map[version.BumpType][]string{}
+	labels := map[version.BumpType]string{}
+	for bump_type, bump := range map[version.BumpType]*Bump{
+		version.Major: c.Bumps.Major,
+		version.Minor: c.Bumps.Minor,
+		version.Patch: c.Bumps.Patch,
+		version.None:  c.Bumps.None,
+	} {
+		if bump != nil {
+			aliases[bump_type] = bump.Aliases
+			labels[bump_type] = bump.Label
+		}
+	}
+	return version.NewVocabulary(aliases, labels)
+}
+
+// Candidate config filenames within the changeset directory
+var CONFIG_FILENAMES = []string{CONFIG_FILENAME, "config.toml", "config.yaml", "config.yml"}
+
+// Python projects may instead configure changesets under [tool.changeset] in pyproject.toml
+const PYPROJECT_FILENAME string = "pyproject.toml"
+
+type pyproject struct {
+	Tool struct {
+		Changeset Config `toml:"changeset"`
+	} `toml:"tool"`
+}
+
+// Returns the paths of all config files present in the root directory
+func findConfigFiles(root string) ([]string, error) {
+	var found []string
+	for _, filename := range CONFIG_FILENAMES {
+		path := filepath.Join(root, CHANGESET_DIRECTORY, filename)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	path := filepath.Join(root, PYPROJECT_FILENAME)
+	contents, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		var project pyproject
+		metadata, err := toml.Decode(string(contents), &project)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if metadata.IsDefined("tool", "changeset") {
+			found = append(found, path)
+		}
+	}
+	return found, nil
+}
+
+// Returns an error naming the keys which don't match any config field
+func unknownFields(keys []string) error {
+	return fmt.Errorf("unknown config field(s): %s", strings.Join(keys, ", "))
+}
+
+func decodeTOML(contents []byte, value interface{}, prefix string) error {
+	metadata, err := toml.Decode(string(contents), value)
 	if err != nil {
-		return Config{}, err
+		return err
+	}
+
+	var unknown []string
+	for _, key := range metadata.Undecoded() {
+		// Other tools' sections of pyproject.toml are expected to be unknown
+		if strings.HasPrefix(key.String(), prefix) {
+			unknown = append(unknown, strings.TrimPrefix(key.String(), prefix))
+		}
+	}
+	if len(unknown) > 0 {
+		return unknownFields(unknown)
 	}
+	return nil
+}
 
+// Decodes the config file based on its extension, rejecting any unknown fields
+func decode(path string, contents []byte) (Config, error) {
 	var config Config
-	err = json.Unmarshal(contents, &config)
+	var err error
+
+	switch {
+	case filepath.Base(path) == PYPROJECT_FILENAME:
+		var project pyproject
+		err = decodeTOML(contents, &project, "tool.changeset.")
+		config = project.Tool.Changeset
+	case strings.HasSuffix(path, ".json"):
+		decoder := json.NewDecoder(bytes.NewReader(contents))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&config)
+	case strings.HasSuffix(path, ".toml"):
+		err = decodeTOML(contents, &config, "")
+	case strings.HasSuffix(path, ".yaml"), strings.HasSuffix(path, ".yml"):
+		decoder := yaml.NewDecoder(bytes.NewReader(contents))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&config)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	default:
+		err = fmt.Errorf("unsupported config format")
+	}
+
 	if err != nil {
-		return Config{}, err
+		return Config{}, fmt.Errorf("%s: %w", path, err)
 	}
 	return config, nil
 }
+
+// Encodes the config in the format matching the extension of the path
+func Encode(path string, config Config) ([]byte, error) {
+	switch {
+	case filepath.Base(path) == PYPROJECT_FILENAME:
+		return nil, fmt.Errorf("writing to %s is not supported, please edit the [tool.changeset] section by hand", PYPROJECT_FILENAME)
+	case strings.HasSuffix(path, ".json"):
+		contents, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(contents, '\n'), nil
+	case strings.HasSuffix(path, ".toml"):
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(config); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case strings.HasSuffix(path, ".yaml"), strings.HasSuffix(path, ".yml"):
+		return yaml.Marshal(config)
+	}
+	return nil, fmt.Errorf("%s: unsupported config format", path)
+}
+
+// Writes the config to the path in the format matching its extension
+func WriteConfig(path string, config Config) error {
+	contents, err := Encode(path, config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0644)
+}
+
+// Returns whether a config file is present in the directory
+func HasConfig(dir string) (bool, error) {
+	found, err := findConfigFiles(dir)
+	return len(found) > 0, err
+}
+
+// Returns the path of the config file, discovering it within the root directory when the path is empty
+func ResolvePath(root string, path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	found, err := findConfigFiles(root)
+	if err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "", ErrNotFound
+	}
+	if len(found) > 1 {
+		return "", fmt.Errorf("multiple config files found, please keep only one of: %s", strings.Join(found, ", "))
+	}
+	return found[0], nil
+}
+
+// Reads the config, applying all layers. See Resolve for the order in which they're applied
+func GetConfig(opts Options) (Config, error) {
+	resolved, err := Resolve(opts)
+	if err != nil {
+		return Config{}, err
+	}
+	return resolved.Config, nil
+}
@@ -0,0 +1,348 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alex-way/changesets/pkg/version"
+)
+
+var expected = Config{
+	Plugin: Plugin{
+		Name:          "versionfile",
+		URL:           "https://example.com/versionfile.wasm",
+		SHA256:        "beef",
+		VersionedFile: ".changeset/version",
+	},
+	Changelog: &Changelog{RepositoryURL: "https://github.com/alex-way/changesets"},
+}
+
+func writeFile(t *testing.T, path string, contents string) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+}
+
+func TestGetConfigFormats(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{
+			"plugin": {"name": "versionfile", "url": "https://example.com/versionfile.wasm", "sha256": "beef", "versionedFile": ".changeset/version"},
+			"changelog": {"repositoryUrl": "https://github.com/alex-way/changesets"}
+		}`,
+		"config.toml": `
+[plugin]
+name = "versionfile"
+url = "https://example.com/versionfile.wasm"
+sha256 = "beef"
+versionedFile = ".changeset/version"
+
+[changelog]
+repositoryUrl = "https://github.com/alex-way/changesets"
+`,
+		"config.yaml": `
+plugin:
+  name: versionfile
+  url: https://example.com/versionfile.wasm
+  sha256: beef
+  versionedFile: .changeset/version
+changelog:
+  repositoryUrl: https://github.com/alex-way/changesets
+`,
+	}
+
+	for filename, contents := range files {
+		t.Run(filename, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, filename), contents)
+
+			config, err := GetConfig(Options{Root: root, Environ: []string{}})
+			assert.NoError(t, err)
+			assert.Equal(t, expected, config)
+		})
+	}
+}
+
+func TestGetConfigPyproject(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, PYPROJECT_FILENAME), `
+[project]
+name = "example"
+version = "1.2.3"
+
+[tool.changeset.plugin]
+name = "versionfile"
+url = "https://example.com/versionfile.wasm"
+sha256 = "beef"
+versionedFile = ".changeset/version"
+
+[tool.changeset.changelog]
+repositoryUrl = "https://github.com/alex-way/changesets"
+`)
+
+	config, err := GetConfig(Options{Root: root, Environ: []string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, config)
+}
+
+func TestGetConfigIgnoresPyprojectWithoutSection(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, PYPROJECT_FILENAME), "[project]\nname = \"example\"\n")
+
+	_, err := GetConfig(Options{Root: root, Environ: []string{}})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGetConfigMultipleFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.json"), "{}")
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.toml"), "")
+
+	_, err := GetConfig(Options{Root: root, Environ: []string{}})
+	assert.ErrorContains(t, err, "multiple config files found")
+}
+
+func TestGetConfigExplicitPath(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "ci", "changeset.yaml")
+	writeFile(t, path, "plugin:\n  name: versionfile\n")
+
+	config, err := GetConfig(Options{Root: root, Path: path, Environ: []string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, "versionfile", config.Plugin.Name)
+
+	// An explicit config file must exist, rather than being treated as optional
+	_, err = GetConfig(Options{Root: root, Path: filepath.Join(root, "missing.json"), Environ: []string{}})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	for _, filename := range CONFIG_FILENAMES {
+		t.Run(filename, func(t *testing.T) {
+			contents, err := Encode(filename, expected)
+			assert.NoError(t, err)
+
+			config, err := decode(filename, contents)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, config)
+		})
+	}
+
+	_, err := Encode(PYPROJECT_FILENAME, expected)
+	assert.Error(t, err)
+}
+
+func TestGetConfigRejectsUnknownFields(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"plugin": {"versionFile": ".changeset/version"}}`,
+		"config.toml": "[plugin]\nversionFile = \".changeset/version\"\n",
+		"config.yaml": "plugin:\n  versionFile: .changeset/version\n",
+	}
+
+	for filename, contents := range files {
+		t.Run(filename, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, filename), contents)
+
+			_, err := GetConfig(Options{Root: root, Environ: []string{}})
+			assert.ErrorContains(t, err, "versionFile")
+		})
+	}
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, PYPROJECT_FILENAME), "[project]\nname = \"example\"\n\n[tool.changeset.plugin]\nversionFile = \"VERSION\"\n")
+	_, err := GetConfig(Options{Root: root, Environ: []string{}})
+	assert.ErrorContains(t, err, "unknown config field(s): plugin.versionFile")
+}
+
+func TestGetConfigAllowsSchema(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"$schema": "./schema.json", "plugin": {"name": "versionfile"}}`,
+		"config.toml": "\"$schema\" = \"./schema.json\"\n\n[plugin]\nname = \"versionfile\"\n",
+		"config.yaml": "$schema: ./schema.json\nplugin:\n  name: versionfile\n",
+	}
+
+	for filename, contents := range files {
+		t.Run(filename, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, filename), contents)
+
+			config, err := GetConfig(Options{Root: root, Environ: []string{}})
+			assert.NoError(t, err)
+			assert.Equal(t, "versionfile", config.Plugin.Name)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "VERSION"), "1.0.0\n")
+
+	valid := Config{Plugin: Plugin{
+		URL:           "https://example.com/plugin.wasm",
+		SHA256:        "beef1de60035053ad01eff83875999dc9918a65e1cffc006fca95c3bfbe55d70",
+		VersionedFile: "VERSION",
+	}}
+	assert.NoError(t, valid.Validate(root))
+
+	invalid := Config{Plugin: Plugin{
+		URL:           "http://example.com/plugin.wasm",
+		SHA256:        "beef",
+		VersionedFile: "missing",
+	}}
+	err := invalid.Validate(root)
+	assert.ErrorContains(t, err, "plugin.url")
+	assert.ErrorContains(t, err, "plugin.sha256")
+	assert.ErrorContains(t, err, "plugin.versionedFile")
+}
+
+func TestValidateTargets(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "VERSION"), "1.0.0\n")
+	writeFile(t, filepath.Join(root, "package.json"), "{}\n")
+
+	valid := Config{Targets: []Plugin{
+		{URL: "https://example.com/versionfile.wasm", VersionedFile: "VERSION"},
+		{URL: "https://example.com/npm.wasm", VersionedFile: "package.json", Source: true},
+	}}
+	assert.NoError(t, valid.Validate(root))
+	assert.Equal(t, "package.json", valid.Source().VersionedFile)
+	assert.Len(t, valid.Plugins(), 2)
+
+	invalid := Config{
+		Plugin: Plugin{URL: "https://example.com/versionfile.wasm"},
+		Targets: []Plugin{
+			{URL: "https://example.com/versionfile.wasm", VersionedFile: "VERSION", Source: true},
+			{URL: "https://example.com/npm.wasm", VersionedFile: "missing.json", Source: true},
+		},
+	}
+	err := invalid.Validate(root)
+	assert.ErrorContains(t, err, "plugin and targets can't both be set")
+	assert.ErrorContains(t, err, "targets[1].versionedFile")
+	assert.ErrorContains(t, err, "only one target can be the source")
+}
+
+func TestValidateLimits(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "VERSION"), "1.0.0\n")
+
+	valid := Config{Plugin: Plugin{URL: "https://example.com/versionfile.wasm", VersionedFile: "VERSION", Limits: &Limits{Timeout: "5s", MemoryPages: 256}}}
+	assert.NoError(t, valid.Validate(root))
+
+	invalid := Config{Plugin: Plugin{
+		URL:           "https://example.com/versionfile.wasm",
+		VersionedFile: "VERSION",
+		Limits:        &Limits{Timeout: "forever", MemoryPages: 70000, OutputBytes: -1},
+	}}
+	err := invalid.Validate(root)
+	assert.ErrorContains(t, err, `plugin.limits.timeout "forever" must be a positive duration`)
+	assert.ErrorContains(t, err, "plugin.limits.memoryPages must be at most 65536")
+	assert.ErrorContains(t, err, "plugin.limits.outputBytes must not be negative")
+}
+
+func TestValidatePluginType(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "VERSION"), "1.0.0\n")
+
+	for _, _plugin := range []Plugin{
+		{URL: "exec://scripts/version.py", VersionedFile: "VERSION"},
+		{URL: "file://scripts/version.py", Type: PLUGIN_TYPE_EXEC, VersionedFile: "VERSION"},
+	} {
+		assert.NoError(t, _plugin.Validate(root))
+		assert.True(t, _plugin.IsExec())
+	}
+	assert.False(t, Plugin{URL: "file://plugin.wasm"}.IsExec())
+
+	err := Plugin{URL: "https://example.com/plugin", Type: PLUGIN_TYPE_EXEC, VersionedFile: "VERSION"}.Validate(root)
+	assert.ErrorContains(t, err, "must be a local file, as executables can't be fetched")
+	err = Plugin{URL: "exec://plugin", Type: PLUGIN_TYPE_WASM, VersionedFile: "VERSION"}.Validate(root)
+	assert.ErrorContains(t, err, `plugin.url "exec://plugin" is an executable, so plugin.type must be exec`)
+	err = Plugin{URL: "file://plugin", Type: "docker", VersionedFile: "VERSION"}.Validate(root)
+	assert.ErrorContains(t, err, `plugin.type "docker" must be one of: wasm, exec`)
+}
+
+func TestValidateSecurity(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "VERSION"), "1.0.0\n")
+	_plugin := Plugin{URL: "https://example.com/versionfile.wasm", VersionedFile: "VERSION"}
+
+	valid := Config{Plugin: _plugin, Security: &Security{
+		TrustedKeys: []string{"RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"},
+		Strict:      true,
+	}}
+	assert.NoError(t, valid.Validate(root))
+
+	err := Config{Plugin: _plugin, Security: &Security{TrustedKeys: []string{"not a key"}}}.Validate(root)
+	assert.ErrorContains(t, err, "security.trustedKeys[0]: invalid minisign public key")
+	err = Config{Plugin: _plugin, Security: &Security{Strict: true}}.Validate(root)
+	assert.ErrorContains(t, err, "security.strict requires at least one of security.trustedKeys")
+}
+
+func TestValidateNetwork(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "VERSION"), "1.0.0\n")
+	_plugin := Plugin{URL: "https://example.com/versionfile.wasm", VersionedFile: "VERSION"}
+
+	valid := Config{Plugin: _plugin, Network: &Network{Mirrors: map[string]string{"https://github.com/": "https://artifacts.example.com/github/"}}}
+	assert.NoError(t, valid.Validate(root))
+
+	err := Config{Plugin: _plugin, Network: &Network{Mirrors: map[string]string{"github.com/": "http://artifacts"}, Retries: -1}}.Validate(root)
+	assert.ErrorContains(t, err, `network.mirrors key "github.com/" must be the start of an https:// URL`)
+	assert.ErrorContains(t, err, `network.mirrors["github.com/"] "http://artifacts" must be an https:// URL`)
+	assert.ErrorContains(t, err, "network.retries must not be negative")
+}
+
+func TestNetworkMirror(t *testing.T) {
+	network := &Network{Mirrors: map[string]string{
+		"https://github.com/":          "https://artifacts.example.com/github/",
+		"https://github.com/alex-way/": "https://artifacts.example.com/alex-way/",
+	}}
+	assert.Equal(t, "https://artifacts.example.com/alex-way/plugin.wasm", network.Mirror("https://github.com/alex-way/plugin.wasm"))
+	assert.Equal(t, "https://artifacts.example.com/github/other/plugin.wasm", network.Mirror("https://github.com/other/plugin.wasm"))
+	assert.Equal(t, "https://example.com/plugin.wasm", network.Mirror("https://example.com/plugin.wasm"))
+
+	var unset *Network
+	assert.Equal(t, "https://github.com/plugin.wasm", unset.Mirror("https://github.com/plugin.wasm"))
+	assert.False(t, unset.IsOffline())
+	assert.Equal(t, DEFAULT_RETRIES, unset.RetryCount())
+}
+
+func TestSourceDefaultsToFirstTarget(t *testing.T) {
+	single := Config{Plugin: Plugin{VersionedFile: "VERSION"}}
+	assert.Equal(t, "VERSION", single.Source().VersionedFile)
+
+	targets := Config{Targets: []Plugin{{VersionedFile: "VERSION"}, {VersionedFile: "Chart.yaml"}}}
+	assert.Equal(t, "VERSION", targets.Source().VersionedFile)
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	assert.Equal(t, SCHEMA_DRAFT, schema["$schema"])
+	assert.Equal(t, false, schema["additionalProperties"])
+	assert.Equal(t, map[string]interface{}{"type": "string"}, schema["properties"].(map[string]interface{})["$schema"])
+
+	plugin := schema["properties"].(map[string]interface{})["plugin"].(map[string]interface{})
+	properties := plugin["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "string"}, properties["versionedFile"])
+	assert.NotContains(t, properties, "versionFile")
+}
+
+func TestVocabulary(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.yaml"), "bumps:\n  major:\n    aliases: [breaking]\n    label: Breaking\n  none:\n    aliases: [chore]\n")
+
+	cfg, err := GetConfig(Options{Root: root, Environ: []string{}})
+	assert.NoError(t, err)
+	vocabulary, err := cfg.Vocabulary()
+	assert.NoError(t, err)
+
+	bump_type, err := vocabulary.Parse("chore")
+	assert.NoError(t, err)
+	assert.Equal(t, version.None, bump_type)
+	assert.Equal(t, "Breaking", vocabulary.Label(version.Major))
+
+	cfg.Bumps.Minor = &Bump{Aliases: []string{"breaking"}}
+	assert.ErrorContains(t, cfg.Validate(root), `alias "breaking" is used by both major and minor`)
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The config key naming the base config a file extends
+const EXTENDS_KEY string = "extends"
+
+// The values of a single config file
+type layer struct {
+	path   string
+	values map[string]interface{}
+}
+
+// Resolves the reference of an extends key, which is either a path or a file:// URI, relative to the extending file
+func resolveExtends(from string, ref string) (string, error) {
+	path := ref
+	if strings.HasPrefix(ref, "file://") {
+		path = strings.TrimPrefix(ref, "file://")
+	} else if strings.Contains(ref, "://") {
+		return "", fmt.Errorf("%s: extends %q must be a path or a file:// URI", from, ref)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+	return filepath.Abs(path)
+}
+
+// Reads the config file and the chain of bases it extends, returning the bases first so that each file is merged
+// over the ones it extends
+func readLayers(path string, chain []string) ([]layer, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, seen := range chain {
+		if seen == absolute {
+			return nil, fmt.Errorf("config extends cycle: %s", strings.Join(append(chain, absolute), " -> "))
+		}
+	}
+	chain = append(chain, absolute)
+
+	values, err := readValues(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, ok := values[EXTENDS_KEY]
+	if !ok {
+		return []layer{{path: path, values: values}}, nil
+	}
+	ref_string, ok := ref.(string)
+	if !ok || ref_string == "" {
+		return nil, fmt.Errorf("%s: extends must be a path or a file:// URI", path)
+	}
+
+	base, err := resolveExtends(path, ref_string)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(base); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s extends %q, which does not exist", path, ref_string)
+		}
+		return nil, err
+	}
+
+	layers, err := readLayers(base, chain)
+	if err != nil {
+		return nil, err
+	}
+	return append(layers, layer{path: path, values: values}), nil
+}
+
+// Rebases the path, relative to the directory of a base config, onto the project root, so that it still names the
+// same file once merged into the project's config
+func rebasePath(path string, dir string, root string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	rebased := filepath.Join(dir, path)
+	if relative, err := filepath.Rel(root, rebased); err == nil {
+		return relative
+	}
+	return rebased
+}
+
+// Rebases a file:// URL, or an exec:// URL naming a path rather than a command on the PATH. Other URLs are kept
+func rebaseURL(url string, dir string, root string) string {
+	for _, scheme := range []string{"file://", "exec://"} {
+		path, ok := strings.CutPrefix(url, scheme)
+		if !ok {
+			continue
+		}
+		if scheme == "exec://" && !strings.ContainsRune(path, '/') {
+			return url
+		}
+		return scheme + rebasePath(path, dir, root)
+	}
+	return url
+}
+
+// Rebases the value at the key when it's a string
+func rebaseValue(values map[string]interface{}, key string, rebase func(string) string) {
+	if value, ok := values[key].(string); ok {
+		values[key] = rebase(value)
+	}
+}
+
+// Rebases the URL and signature of a plugin or target
+func rebasePlugin(values map[string]interface{}, dir string, root string) {
+	rebaseValue(values, "url", func(url string) string { return rebaseURL(url, dir, root) })
+	rebaseValue(values, "signature", func(signature string) string {
+		if strings.Contains(signature, "://") {
+			return rebaseURL(signature, dir, root)
+		}
+		return rebasePath(signature, dir, root)
+	})
+}
+
+// Rebases the plugin URLs and signatures set by a base config, which are relative to the base file, onto the project
+// root. Other paths, such as the versioned file and the changelog, name files of each project extending the base
+func rebaseLayer(values map[string]interface{}, path string, root string) {
+	dir := filepath.Dir(path)
+	if root == "" {
+		root = "."
+	}
+	if absolute, err := filepath.Abs(root); err == nil {
+		root = absolute
+	}
+
+	if _plugin, ok := values["plugin"].(map[string]interface{}); ok {
+		rebasePlugin(_plugin, dir, root)
+	}
+	switch targets := values["targets"].(type) {
+	case []interface{}:
+		for _, target := range targets {
+			if target, ok := target.(map[string]interface{}); ok {
+				rebasePlugin(target, dir, root)
+			}
+		}
+	case []map[string]interface{}:
+		for _, target := range targets {
+			rebasePlugin(target, dir, root)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Local, uncommitted overrides of the committed config within the changeset directory
+var LOCAL_CONFIG_FILENAMES = []string{"config.local.json", "config.local.toml", "config.local.yaml", "config.local.yml"}
+
+// The prefix of environment variables overriding config fields, e.g. CHANGESET_PLUGIN_URL
+const ENV_PREFIX string = "CHANGESET_"
+
+const ORIGIN_DEFAULT string = "default"
+
+// Values applied before any config file
+var DEFAULTS = map[string]interface{}{
+	"plugin": map[string]interface{}{
+		"versionedFile": ".changeset/version",
+	},
+}
+
+type Options struct {
+	// The project root used to discover config files
+	Root string
+	// An explicit config file, discovered from Root when empty
+	Path string
+	// Overrides from the command line in the form key=value, e.g. plugin.url=https://example.com/plugin.wasm
+	Overrides []string
+	// The environment CHANGESET_* variables are read from, defaults to os.Environ()
+	Environ []string
+}
+
+// The config after applying all layers
+type Resolved struct {
+	Config Config
+	// The project root the config was resolved from
+	Root string
+	// The committed config file
+	Path string
+	// The local config file, if any
+	LocalPath string
+	// The base configs extended by the config files, in the order they were applied
+	Bases []string
+	// The merged values keyed by field, e.g. {"plugin": {"url": ...}}
+	Values map[string]interface{}
+	// The layer which set each field, keyed by dotted path such as plugin.url
+	Origins map[string]string
+}
+
+// Returns the local config file within the root directory, if there is one
+func findLocalConfigFile(root string) (string, error) {
+	var found []string
+	for _, filename := range LOCAL_CONFIG_FILENAMES {
+		path := filepath.Join(root, CHANGESET_DIRECTORY, filename)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	if len(found) > 1 {
+		return "", fmt.Errorf("multiple local config files found, please keep only one of: %s", strings.Join(found, ", "))
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+// Decodes the config file into a map so that only the fields it sets are applied
+func decodeValues(path string, contents []byte) (map[string]interface{}, error) {
+	// Decode into the struct first so that unknown fields are reported against the file
+	if _, err := decode(path, contents); err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	var err error
+	switch {
+	case filepath.Base(path) == PYPROJECT_FILENAME:
+		var project map[string]interface{}
+		_, err = toml.Decode(string(contents), &project)
+		if tool, ok := project["tool"].(map[string]interface{}); ok {
+			if changeset, ok := tool["changeset"].(map[string]interface{}); ok {
+				values = changeset
+			}
+		}
+	case strings.HasSuffix(path, ".json"):
+		err = json.Unmarshal(contents, &values)
+	case strings.HasSuffix(path, ".toml"):
+		_, err = toml.Decode(string(contents), &values)
+	case strings.HasSuffix(path, ".yaml"), strings.HasSuffix(path, ".yml"):
+		err = yaml.Unmarshal(contents, &values)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return values, nil
+}
+
+func readValues(path string) (map[string]interface{}, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValues(path, contents)
+}
+
+func joinPath(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// Deep merges src into dst, recording the origin of every field set by src. Lists are replaced rather than merged
+func merge(dst map[string]interface{}, src map[string]interface{}, origin string, prefix string, origins map[string]string) {
+	for key, value := range src {
+		path := joinPath(prefix, key)
+		if src_map, ok := value.(map[string]interface{}); ok {
+			dst_map, ok := dst[key].(map[string]interface{})
+			if !ok {
+				dst_map = map[string]interface{}{}
+				dst[key] = dst_map
+			}
+			merge(dst_map, src_map, origin, path, origins)
+			continue
+		}
+		dst[key] = value
+		origins[path] = origin
+	}
+}
+
+// Sets the value at the dotted path, creating intermediate maps as needed
+func setValue(values map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := values[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			values[key] = next
+		}
+		values = next
+	}
+	values[keys[len(keys)-1]] = value
+}
+
+func jsonName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+// Returns the type of the field at the dotted path. Any key below a map field is accepted
+func fieldType(path string) (reflect.Type, error) {
+	t := reflect.TypeOf(Config{})
+	for _, key := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		switch t.Kind() {
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			found := false
+			for i := 0; i < t.NumField(); i++ {
+				if field := t.Field(i); field.IsExported() && jsonName(field) == key {
+					t = field.Type
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unknown config field: %s", path)
+			}
+		default:
+			return nil, fmt.Errorf("unknown config field: %s", path)
+		}
+	}
+	return t, nil
+}
+
+// Parses the string value of an environment variable or flag into the type of the field
+func parseValue(t reflect.Type, raw string) (interface{}, error) {
+	switch t.Kind() {
+	case reflect.String, reflect.Interface:
+		return raw, nil
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseInt(raw, 10, 64)
+	case reflect.Slice:
+		var values []interface{}
+		for _, item := range strings.Split(raw, ",") {
+			value, err := parseValue(t.Elem(), strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("fields of type %s can't be set from a string", t)
+}
+
+// Converts a camelCase field name into the SCREAMING_SNAKE_CASE used by environment variables
+func envName(key string) string {
+	var builder strings.Builder
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 {
+			builder.WriteRune('_')
+		}
+		builder.WriteRune(unicode.ToUpper(r))
+	}
+	return builder.String()
+}
+
+// Returns the dotted paths of all fields which can be set from a single string, keyed by environment variable name
+func envFields(t reflect.Type, prefix string, env_prefix string, fields map[string]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if !field.IsExported() || name == "" || name == "-" || (prefix == "" && (name == EXTENDS_KEY || name == SCHEMA_KEY)) {
+			continue
+		}
+
+		path := joinPath(prefix, name)
+		env := env_prefix + "_" + envName(name)
+		field_type := field.Type
+		for field_type.Kind() == reflect.Pointer {
+			field_type = field_type.Elem()
+		}
+
+		switch field_type.Kind() {
+		case reflect.Struct:
+			envFields(field_type, path, env, fields)
+		case reflect.Map:
+			// Arbitrary keys can't be mapped back from environment variable names
+		case reflect.Slice:
+			// Lists of objects, such as targets, can't be set from a single string
+			if field_type.Elem().Kind() != reflect.Struct {
+				fields[env] = path
+			}
+		default:
+			fields[env] = path
+		}
+	}
+}
+
+func envValues(environ []string, origins map[string]string, values map[string]interface{}) error {
+	fields := map[string]string{}
+	envFields(reflect.TypeOf(Config{}), "", strings.TrimSuffix(ENV_PREFIX, "_"), fields)
+
+	for _, variable := range environ {
+		name, raw, ok := strings.Cut(variable, "=")
+		if !ok || !strings.HasPrefix(name, ENV_PREFIX) {
+			continue
+		}
+		path, ok := fields[name]
+		if !ok {
+			continue
+		}
+
+		t, err := fieldType(path)
+		if err != nil {
+			return err
+		}
+		value, err := parseValue(t, raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		setValue(values, path, value)
+		origins[path] = "env " + name
+	}
+	return nil
+}
+
+func overrideValues(overrides []string, origins map[string]string, values map[string]interface{}) error {
+	for _, override := range overrides {
+		path, raw, ok := strings.Cut(override, "=")
+		if !ok {
+			return fmt.Errorf("invalid override %q, expected key=value", override)
+		}
+		if path == EXTENDS_KEY {
+			return fmt.Errorf("%s can only be set in a config file", EXTENDS_KEY)
+		}
+
+		t, err := fieldType(path)
+		if err != nil {
+			return err
+		}
+		value, err := parseValue(t, raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		setValue(values, path, value)
+		origins[path] = "flag --set " + path
+	}
+	return nil
+}
+
+// Decodes the merged values into the config, rejecting unknown fields
+func fromValues(values map[string]interface{}) (Config, error) {
+	contents, err := json.Marshal(values)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	return config, nil
+}
+
+// Applies the config layers in order of precedence: defaults, the committed config file, the local config file,
+// CHANGESET_* environment variables and finally overrides from the command line. Each config file is applied over
+// the bases it extends
+func Resolve(opts Options) (Resolved, error) {
+	resolved := Resolved{Root: opts.Root, Values: map[string]interface{}{}, Origins: map[string]string{}}
+	merge(resolved.Values, DEFAULTS, ORIGIN_DEFAULT, "", resolved.Origins)
+
+	path, err := ResolvePath(opts.Root, opts.Path)
+	if err != nil && err != ErrNotFound {
+		return Resolved{}, err
+	}
+	resolved.Path = path
+
+	resolved.LocalPath, err = findLocalConfigFile(opts.Root)
+	if err != nil {
+		return Resolved{}, err
+	}
+
+	if resolved.Path == "" && resolved.LocalPath == "" {
+		return Resolved{}, ErrNotFound
+	}
+
+	for _, file := range []string{resolved.Path, resolved.LocalPath} {
+		if file == "" {
+			continue
+		}
+		layers, err := readLayers(file, nil)
+		if err != nil {
+			return Resolved{}, err
+		}
+		for _, layer := range layers {
+			if layer.path != file {
+				resolved.Bases = append(resolved.Bases, layer.path)
+				rebaseLayer(layer.values, layer.path, opts.Root)
+			}
+			merge(resolved.Values, layer.values, layer.path, "", resolved.Origins)
+		}
+	}
+
+	environ := opts.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+	if err := envValues(environ, resolved.Origins, resolved.Values); err != nil {
+		return Resolved{}, err
+	}
+
+	if err := overrideValues(opts.Overrides, resolved.Origins, resolved.Values); err != nil {
+		return Resolved{}, err
+	}
+
+	resolved.Config, err = fromValues(resolved.Values)
+	if err != nil {
+		return Resolved{}, err
+	}
+	return resolved, nil
+}
+
+// Returns the value at the dotted path of the merged values
+func (r Resolved) Value(path string) interface{} {
+	var value interface{} = r.Values
+	for _, key := range strings.Split(path, ".") {
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = values[key]
+	}
+	return value
+}
+
+// Returns the dotted paths of all fields which were set, sorted alphabetically
+func (r Resolved) Fields() []string {
+	var fields []string
+	for field := range r.Origins {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
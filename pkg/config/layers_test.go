@@ -0,0 +1,187 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveLayers(t *testing.T) {
+	root := t.TempDir()
+	committed := filepath.Join(root, CHANGESET_DIRECTORY, "config.json")
+	local := filepath.Join(root, CHANGESET_DIRECTORY, "config.local.yaml")
+	writeFile(t, committed, `{
+		"plugin": {"name": "versionfile", "url": "https://example.com/plugin.wasm", "sha256": "beef"},
+		"changelog": {"repositoryUrl": "https://github.com/alex-way/changesets", "contributors": {"botPatterns": ["bot"]}}
+	}`)
+	writeFile(t, local, "plugin:\n  url: file://plugin.wasm\n")
+
+	resolved, err := Resolve(Options{
+		Root:      root,
+		Overrides: []string{"plugin.sha256=cafe"},
+		Environ: []string{
+			"CHANGESET_PLUGIN_SHA256=f00d",
+			"CHANGESET_PLUGIN_VERSIONED_FILE=VERSION",
+			"CHANGESET_CHANGELOG_CONTRIBUTORS_BOT_PATTERNS=dependabot, renovate",
+			"CHANGESET_CHANGELOG_UNRELEASED=true",
+			"CHANGESET_UNRELATED=ignored",
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, Plugin{Name: "versionfile", URL: "file://plugin.wasm", SHA256: "cafe", VersionedFile: "VERSION"}, resolved.Config.Plugin)
+	assert.Equal(t, []string{"dependabot", "renovate"}, resolved.Config.Changelog.Contributors.BotPatterns)
+	assert.True(t, resolved.Config.Changelog.Unreleased)
+	assert.Equal(t, "https://github.com/alex-way/changesets", resolved.Config.Changelog.RepositoryURL)
+
+	assert.Equal(t, committed, resolved.Origins["plugin.name"])
+	assert.Equal(t, local, resolved.Origins["plugin.url"])
+	assert.Equal(t, "flag --set plugin.sha256", resolved.Origins["plugin.sha256"])
+	assert.Equal(t, "env CHANGESET_PLUGIN_VERSIONED_FILE", resolved.Origins["plugin.versionedFile"])
+	assert.Equal(t, "file://plugin.wasm", resolved.Value("plugin.url"))
+}
+
+func TestResolveOfflineFromTheEnvironment(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.toml"), "[plugin]\nname = \"versionfile\"\n")
+
+	resolved, err := Resolve(Options{Root: root, Environ: []string{"CHANGESET_NETWORK_OFFLINE=true"}})
+	assert.NoError(t, err)
+	assert.True(t, resolved.Config.Network.IsOffline())
+	assert.Equal(t, "env CHANGESET_NETWORK_OFFLINE", resolved.Origins["network.offline"])
+}
+
+func TestResolveDefaults(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.toml"), "[plugin]\nname = \"versionfile\"\n")
+
+	resolved, err := Resolve(Options{Root: root, Environ: []string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, ".changeset/version", resolved.Config.Plugin.VersionedFile)
+	assert.Equal(t, ORIGIN_DEFAULT, resolved.Origins["plugin.versionedFile"])
+}
+
+func TestResolveInvalidOverrides(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.json"), "{}")
+
+	_, err := Resolve(Options{Root: root, Overrides: []string{"plugin.versionFile=VERSION"}, Environ: []string{}})
+	assert.ErrorContains(t, err, "unknown config field: plugin.versionFile")
+
+	_, err = Resolve(Options{Root: root, Overrides: []string{"plugin.url"}, Environ: []string{}})
+	assert.ErrorContains(t, err, "expected key=value")
+
+	_, err = Resolve(Options{Root: root, Environ: []string{"CHANGESET_CHANGELOG_UNRELEASED=maybe"}})
+	assert.ErrorContains(t, err, "CHANGESET_CHANGELOG_UNRELEASED")
+}
+
+func TestResolveWithoutConfig(t *testing.T) {
+	_, err := Resolve(Options{Root: t.TempDir(), Environ: []string{}})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestResolvePluginSettings(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.yaml"), "plugin:\n  name: pyproject\n  settings:\n    table: project\n    strict: true\n")
+
+	resolved, err := Resolve(Options{Root: root, Overrides: []string{"plugin.settings.table=tool.poetry"}, Environ: []string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"table": "tool.poetry", "strict": true}, resolved.Config.Plugin.Settings)
+	assert.Equal(t, "flag --set plugin.settings.table", resolved.Origins["plugin.settings.table"])
+}
+
+func TestResolveExtends(t *testing.T) {
+	root := t.TempDir()
+	shared := filepath.Join(root, "shared")
+	org := filepath.Join(shared, "org.yaml")
+	team := filepath.Join(shared, "team.json")
+	committed := filepath.Join(root, CHANGESET_DIRECTORY, "config.toml")
+
+	writeFile(t, org, "plugin:\n  name: versionfile\n  url: https://example.com/v1.wasm\n  settings:\n    a: org\n    b: org\nchangelog:\n  contributors:\n    botPatterns: [bot, renovate]\n")
+	writeFile(t, team, `{"extends": "file://org.yaml", "plugin": {"url": "https://example.com/v2.wasm", "settings": {"b": "team"}}}`)
+	writeFile(t, committed, "extends = \"../shared/team.json\"\n\n[changelog.contributors]\nbotPatterns = [\"dependabot\"]\n")
+
+	resolved, err := Resolve(Options{Root: root, Environ: []string{}})
+	assert.NoError(t, err)
+
+	absolute := func(path string) string {
+		path, err := filepath.Abs(path)
+		assert.NoError(t, err)
+		return path
+	}
+	assert.Equal(t, []string{absolute(org), absolute(team)}, resolved.Bases)
+
+	// Maps are merged key by key while lists are replaced
+	assert.Equal(t, "versionfile", resolved.Config.Plugin.Name)
+	assert.Equal(t, "https://example.com/v2.wasm", resolved.Config.Plugin.URL)
+	assert.Equal(t, map[string]interface{}{"a": "org", "b": "team"}, resolved.Config.Plugin.Settings)
+	assert.Equal(t, []string{"dependabot"}, resolved.Config.Changelog.Contributors.BotPatterns)
+	assert.Equal(t, absolute(org), resolved.Origins["plugin.name"])
+	assert.Equal(t, committed, resolved.Origins["changelog.contributors.botPatterns"])
+}
+
+func TestResolveExtendsRebasesPaths(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "project")
+	writeFile(t, filepath.Join(dir, "shared", "base.yaml"), `
+plugin:
+  name: versionfile
+  url: file://plugins/versionfile.wasm
+  signature: plugins/versionfile.wasm.minisig
+  changelogFile: CHANGES.md
+  versionedFile: VERSION
+targets:
+  - name: script
+    url: exec://scripts/version.sh
+  - name: installed
+    url: exec://version-tool
+  - name: remote
+    url: https://example.com/plugin.wasm
+    signature: file:///keys/plugin.minisig
+changelog:
+  file: docs/CHANGELOG.md
+migration:
+  file: docs/MIGRATING.md
+`)
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.json"), `{"extends": "../../shared/base.yaml", "changelog": {"file": "CHANGELOG.md"}}`)
+
+	resolved, err := Resolve(Options{Root: root, Environ: []string{}})
+	assert.NoError(t, err)
+
+	// Plugins are relative to the base file, while other paths name files of the project
+	assert.Equal(t, "file://../shared/plugins/versionfile.wasm", resolved.Config.Plugin.URL)
+	assert.Equal(t, "../shared/plugins/versionfile.wasm.minisig", resolved.Config.Plugin.Signature)
+	assert.Equal(t, "CHANGES.md", resolved.Config.Plugin.ChangelogFile)
+	assert.Equal(t, "VERSION", resolved.Config.Plugin.VersionedFile)
+	assert.Equal(t, "exec://../shared/scripts/version.sh", resolved.Config.Targets[0].URL)
+	assert.Equal(t, "exec://version-tool", resolved.Config.Targets[1].URL)
+	assert.Equal(t, "https://example.com/plugin.wasm", resolved.Config.Targets[2].URL)
+	assert.Equal(t, "file:///keys/plugin.minisig", resolved.Config.Targets[2].Signature)
+	assert.Equal(t, "docs/MIGRATING.md", resolved.Config.Migration.File)
+	// Paths set by the project itself aren't rebased
+	assert.Equal(t, "CHANGELOG.md", resolved.Config.Changelog.File)
+}
+
+func TestResolveExtendsErrors(t *testing.T) {
+	root := t.TempDir()
+	committed := filepath.Join(root, CHANGESET_DIRECTORY, "config.json")
+
+	writeFile(t, committed, `{"extends": "missing.json"}`)
+	_, err := Resolve(Options{Root: root, Environ: []string{}})
+	assert.ErrorContains(t, err, `extends "missing.json", which does not exist`)
+
+	writeFile(t, committed, `{"extends": "https://example.com/config.json"}`)
+	_, err = Resolve(Options{Root: root, Environ: []string{}})
+	assert.ErrorContains(t, err, "must be a path or a file:// URI")
+
+	writeFile(t, committed, `{"extends": "base.json"}`)
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "base.json"), `{"extends": "config.json"}`)
+	_, err = Resolve(Options{Root: root, Environ: []string{}})
+	assert.ErrorContains(t, err, "config extends cycle")
+	assert.ErrorContains(t, err, "config.json -> ")
+
+	writeFile(t, committed, `{}`)
+	_, err = Resolve(Options{Root: root, Overrides: []string{"extends=base.json"}, Environ: []string{}})
+	assert.ErrorContains(t, err, "extends can only be set in a config file")
+}
@@ -0,0 +1,446 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Whether the origin of a field is a config file rather than the defaults, the environment or a flag
+func isFileOrigin(origin string) bool {
+	return origin != "" && origin != ORIGIN_DEFAULT && !strings.HasPrefix(origin, "env ") && !strings.HasPrefix(origin, "flag ")
+}
+
+// Whether the path is within the directory
+func isWithin(dir string, path string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// The object whose sha256 is pinned: the plugin table, or the target at the index of the targets list
+type pinTarget struct {
+	keys  []string
+	index int
+}
+
+func (p pinTarget) String() string {
+	if p.index < 0 {
+		return strings.Join(p.keys, ".")
+	}
+	return fmt.Sprintf("%s[%d]", strings.Join(p.keys, "."), p.index)
+}
+
+// Sets the sha256 of the plugin in the config file which configures it, returning the path of the file. The plugin
+// is the target at the index when targets are used, and the single plugin otherwise. Only the sha256 is edited, so
+// the rest of the file, including its comments and the order of its keys, is kept as it is. Files outside the
+// project, such as the bases it extends, are shared with other projects and are never written
+func (r Resolved) PinChecksum(index int, sum string) (string, error) {
+	field := "plugin.sha256"
+	target := pinTarget{keys: []string{"plugin"}, index: -1}
+	if len(r.Config.Targets) > 0 {
+		field = "targets"
+		target = pinTarget{keys: []string{"targets"}, index: index}
+	} else if _, ok := r.Origins[field]; !ok {
+		field = "plugin.url"
+	}
+
+	origin := r.Origins[field]
+	if !isFileOrigin(origin) {
+		return "", fmt.Errorf("%s is set by %s rather than a config file, so its sha256 must be updated by hand", field, origin)
+	}
+	if !isWithin(r.Root, origin) {
+		return "", fmt.Errorf("%s is set by %s, which is outside of the project, so its sha256 must be updated by hand", field, origin)
+	}
+
+	contents, err := os.ReadFile(origin)
+	if err != nil {
+		return "", err
+	}
+	var pinned []byte
+	switch {
+	case filepath.Base(origin) == PYPROJECT_FILENAME:
+		target.keys = append([]string{"tool", "changeset"}, target.keys...)
+		pinned, err = pinTOML(contents, target, sum)
+	case strings.HasSuffix(origin, ".json"):
+		pinned, err = pinJSON(contents, target, sum)
+	case strings.HasSuffix(origin, ".toml"):
+		pinned, err = pinTOML(contents, target, sum)
+	case strings.HasSuffix(origin, ".yaml"), strings.HasSuffix(origin, ".yml"):
+		pinned, err = pinYAML(contents, target, sum)
+	default:
+		err = fmt.Errorf("unsupported config format")
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w, so its sha256 must be updated by hand", origin, err)
+	}
+
+	// Guards against an edit which doesn't parse back to the checksum, rather than writing a broken config
+	values, err := decodeValues(origin, pinned)
+	if err != nil {
+		return "", err
+	}
+	if pinnedSum(values, target) != sum {
+		return "", fmt.Errorf("%s: failed to set the sha256 of %s, so it must be updated by hand", origin, target)
+	}
+	return origin, os.WriteFile(origin, pinned, 0644)
+}
+
+// Returns the sha256 of the object in the decoded config values
+func pinnedSum(values map[string]interface{}, target pinTarget) string {
+	keys := target.keys
+	if keys[0] == "tool" {
+		keys = keys[2:]
+	}
+	var object interface{} = values
+	for _, key := range keys {
+		values, ok := object.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		object = values[key]
+	}
+	if target.index >= 0 {
+		switch list := object.(type) {
+		case []interface{}:
+			if target.index >= len(list) {
+				return ""
+			}
+			object = list[target.index]
+		case []map[string]interface{}:
+			if target.index >= len(list) {
+				return ""
+			}
+			object = list[target.index]
+		}
+	}
+	values, _ = object.(map[string]interface{})
+	sum, _ := values["sha256"].(string)
+	return sum
+}
+
+// Returns the contents with the span between the offsets replaced
+func splice(contents []byte, start int, end int, replacement string) []byte {
+	spliced := append([]byte{}, contents[:start]...)
+	spliced = append(spliced, replacement...)
+	return append(spliced, contents[end:]...)
+}
+
+// Returns the leading whitespace of the line containing the offset
+func lineIndent(contents []byte, offset int) string {
+	start := bytes.LastIndexByte(contents[:offset], '\n') + 1
+	end := start
+	for end < len(contents) && (contents[end] == ' ' || contents[end] == '\t') {
+		end++
+	}
+	return string(contents[start:end])
+}
+
+// Reads past the JSON value at the start of the decoder, whether it's a scalar, an object or an array
+func skipJSON(dec *json.Decoder) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); ok && (delim == '{' || delim == '[') {
+		for dec.More() {
+			if delim == '{' {
+				if _, err := dec.Token(); err != nil {
+					return err
+				}
+			}
+			if err := skipJSON(dec); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	}
+	return err
+}
+
+// Reads the opening delimiter of the next JSON value
+func expectJSON(dec *json.Decoder, expected json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != expected {
+		return fmt.Errorf("expected %s", string(expected))
+	}
+	return nil
+}
+
+// Sets the sha256 of the object in the JSON, by replacing its value or adding it after the last member of the object
+func pinJSON(contents []byte, target pinTarget, sum string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	if err := expectJSON(dec, '{'); err != nil {
+		return nil, err
+	}
+	for _, key := range target.keys {
+		found := false
+		for dec.More() {
+			name, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if name == key {
+				found = true
+				break
+			}
+			if err := skipJSON(dec); err != nil {
+				return nil, err
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s not found", target)
+		}
+		if target.index < 0 || key != target.keys[len(target.keys)-1] {
+			if err := expectJSON(dec, '{'); err != nil {
+				return nil, fmt.Errorf("%s is not an object", target)
+			}
+		}
+	}
+	if target.index >= 0 {
+		if err := expectJSON(dec, '['); err != nil {
+			return nil, fmt.Errorf("%s is not a list", strings.Join(target.keys, "."))
+		}
+		for i := 0; i < target.index; i++ {
+			if !dec.More() {
+				return nil, fmt.Errorf("%s not found", target)
+			}
+			if err := skipJSON(dec); err != nil {
+				return nil, err
+			}
+		}
+		if err := expectJSON(dec, '{'); err != nil {
+			return nil, fmt.Errorf("%s is not an object", target)
+		}
+	}
+
+	value, err := json.Marshal(sum)
+	if err != nil {
+		return nil, err
+	}
+	open := int(dec.InputOffset())
+	last := -1
+	for dec.More() {
+		name, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if name == "sha256" {
+			start := int(dec.InputOffset())
+			for start < len(contents) && strings.ContainsRune(" \t\r\n:", rune(contents[start])) {
+				start++
+			}
+			if err := skipJSON(dec); err != nil {
+				return nil, err
+			}
+			return splice(contents, start, int(dec.InputOffset()), string(value)), nil
+		}
+		if err := skipJSON(dec); err != nil {
+			return nil, err
+		}
+		last = int(dec.InputOffset())
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	closing := int(dec.InputOffset()) - 1
+
+	member := fmt.Sprintf(`"sha256": %s`, value)
+	if last < 0 {
+		return splice(contents, open, open, member), nil
+	}
+	if !bytes.ContainsRune(contents[last:closing], '\n') {
+		return splice(contents, last, last, ", "+member), nil
+	}
+	return splice(contents, last, last, ",\n"+lineIndent(contents, last)+member), nil
+}
+
+// Returns the last line of the YAML node and everything within it
+func lastYAMLLine(node *yaml.Node) int {
+	line := node.Line
+	for _, child := range node.Content {
+		if child_line := lastYAMLLine(child); child_line > line {
+			line = child_line
+		}
+	}
+	return line
+}
+
+// Returns the value of the key in the YAML mapping
+func yamlValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// Sets the sha256 of the object in the YAML, by replacing its value or adding it on a new line after the object
+func pinYAML(contents []byte, target pinTarget, sum string) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(contents, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("%s not found", target)
+	}
+	object := document.Content[0]
+	for _, key := range target.keys {
+		if object.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s not found", target)
+		}
+		if object = yamlValue(object, key); object == nil {
+			return nil, fmt.Errorf("%s not found", target)
+		}
+	}
+	if target.index >= 0 {
+		if object.Kind != yaml.SequenceNode || target.index >= len(object.Content) {
+			return nil, fmt.Errorf("%s not found", target)
+		}
+		object = object.Content[target.index]
+	}
+	if object.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s is not an object", target)
+	}
+	if object.Style&yaml.FlowStyle != 0 || len(object.Content) == 0 {
+		return nil, fmt.Errorf("%s is not a block mapping", target)
+	}
+
+	lines := bytes.SplitAfter(contents, []byte("\n"))
+	if value := yamlValue(object, "sha256"); value != nil {
+		if value.Kind != yaml.ScalarNode || value.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return nil, fmt.Errorf("%s.sha256 is not a string", target)
+		}
+		line := lines[value.Line-1]
+		start := len(string([]rune(string(line))[:value.Column-1]))
+		end := start
+		replacement := sum
+		switch {
+		case value.Style&yaml.DoubleQuotedStyle != 0, value.Style&yaml.SingleQuotedStyle != 0:
+			quote := line[start]
+			end = start + 1 + bytes.IndexByte(line[start+1:], quote) + 1
+			replacement = string(quote) + sum + string(quote)
+		default:
+			end = start + len(value.Value)
+		}
+		lines[value.Line-1] = splice(line, start, end, replacement)
+		return bytes.Join(lines, nil), nil
+	}
+
+	// The new key is indented like the first key of the mapping, which follows the "- " of a list item
+	first := object.Content[0]
+	indent := strings.Repeat(" ", first.Column-1)
+	after := lastYAMLLine(object)
+	if after > len(lines) {
+		after = len(lines)
+	}
+	if !bytes.HasSuffix(lines[after-1], []byte("\n")) {
+		lines[after-1] = append(lines[after-1], '\n')
+	}
+	inserted := append([][]byte{}, lines[:after]...)
+	inserted = append(inserted, []byte(indent+"sha256: "+sum+"\n"))
+	return bytes.Join(append(inserted, lines[after:]...), nil), nil
+}
+
+// Returns the name of the TOML table the line opens, and whether it's an array of tables
+func tomlHeader(line string) (string, bool, bool) {
+	line = strings.TrimSpace(line)
+	if comment := strings.Index(line, "#"); comment >= 0 {
+		line = strings.TrimSpace(line[:comment])
+	}
+	if strings.HasPrefix(line, "[[") && strings.HasSuffix(line, "]]") {
+		return normalizeTOMLKey(line[2 : len(line)-2]), true, true
+	}
+	if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+		return normalizeTOMLKey(line[1 : len(line)-1]), false, true
+	}
+	return "", false, false
+}
+
+// Returns the dotted key without whitespace or quotes around its parts
+func normalizeTOMLKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Sets the sha256 of the table in the TOML, by replacing its value or adding it after the last line of the table. The
+// table must be written as a [table] or [[table]] section, rather than inline or with dotted keys
+func pinTOML(contents []byte, target pinTarget, sum string) ([]byte, error) {
+	table := strings.Join(target.keys, ".")
+	lines := bytes.SplitAfter(contents, []byte("\n"))
+
+	start, seen := -1, 0
+	for i, line := range lines {
+		name, array, ok := tomlHeader(string(line))
+		if !ok || name != table || array != (target.index >= 0) {
+			continue
+		}
+		if target.index < 0 || seen == target.index {
+			start = i + 1
+			break
+		}
+		seen++
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("%s is not a [%s] section", target, table)
+	}
+
+	last := start - 1
+	for i := start; i < len(lines); i++ {
+		line := string(lines[i])
+		if _, _, ok := tomlHeader(line); ok {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		last = i
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || normalizeTOMLKey(key) != "sha256" {
+			continue
+		}
+		offset := len(key) + 1 + len(value) - len(strings.TrimLeft(value, " \t"))
+		quote := line[offset]
+		if quote != '"' && quote != '\'' {
+			return nil, fmt.Errorf("%s.sha256 is not a string", target)
+		}
+		end := strings.IndexByte(line[offset+1:], quote)
+		if end < 0 {
+			return nil, fmt.Errorf("%s.sha256 is not a string", target)
+		}
+		lines[i] = splice(lines[i], offset, offset+1+end+1, string(quote)+sum+string(quote))
+		return bytes.Join(lines, nil), nil
+	}
+
+	indent := ""
+	if last >= start {
+		indent = lineIndent(lines[last], 0)
+	}
+	if !bytes.HasSuffix(lines[last], []byte("\n")) {
+		lines[last] = append(lines[last], '\n')
+	}
+	inserted := append([][]byte{}, lines[:last+1]...)
+	inserted = append(inserted, []byte(fmt.Sprintf("%ssha256 = %q\n", indent, sum)))
+	return bytes.Join(append(inserted, lines[last+1:]...), nil), nil
+}
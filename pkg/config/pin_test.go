@@ -0,0 +1,152 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinChecksum(t *testing.T) {
+	root := t.TempDir()
+	committed := filepath.Join(root, CHANGESET_DIRECTORY, "config.json")
+	writeFile(t, committed, `{"plugin": {"name": "versionfile", "url": "https://example.com/plugin.wasm", "sha256": "beef"}}`)
+
+	resolved, err := Resolve(Options{Root: root, Environ: []string{}})
+	require.NoError(t, err)
+	path, err := resolved.PinChecksum(0, "cafe")
+	require.NoError(t, err)
+	assert.Equal(t, committed, path)
+
+	resolved, err = Resolve(Options{Root: root, Environ: []string{}})
+	require.NoError(t, err)
+	assert.Equal(t, "cafe", resolved.Config.Plugin.SHA256)
+	assert.Equal(t, "https://example.com/plugin.wasm", resolved.Config.Plugin.URL)
+	// Defaults aren't written into the file
+	assert.Equal(t, ORIGIN_DEFAULT, resolved.Origins["plugin.versionedFile"])
+}
+
+func TestPinChecksumOfTarget(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.toml"), `
+[[targets]]
+name = "npm"
+url = "https://example.com/npm.wasm"
+versionedFile = "package.json"
+
+[[targets]]
+name = "helm"
+url = "https://example.com/helm.wasm"
+versionedFile = "Chart.yaml"
+`)
+
+	resolved, err := Resolve(Options{Root: root, Environ: []string{}})
+	require.NoError(t, err)
+	_, err = resolved.PinChecksum(1, "cafe")
+	require.NoError(t, err)
+
+	resolved, err = Resolve(Options{Root: root, Environ: []string{}})
+	require.NoError(t, err)
+	assert.Equal(t, "", resolved.Config.Targets[0].SHA256)
+	assert.Equal(t, "cafe", resolved.Config.Targets[1].SHA256)
+}
+
+func TestPinChecksumOutsideOfAFile(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.yaml"), "plugin:\n  name: versionfile\n  url: https://example.com/plugin.wasm\n")
+
+	resolved, err := Resolve(Options{Root: root, Environ: []string{"CHANGESET_PLUGIN_SHA256=beef"}})
+	require.NoError(t, err)
+	_, err = resolved.PinChecksum(0, "cafe")
+	assert.EqualError(t, err, "plugin.sha256 is set by env CHANGESET_PLUGIN_SHA256 rather than a config file, so its sha256 must be updated by hand")
+}
+
+func TestPinChecksumOnlyEditsTheChecksum(t *testing.T) {
+	tests := []struct {
+		filename string
+		before   string
+		after    string
+	}{
+		{
+			filename: "config.json",
+			before:   "{\n  \"plugin\": {\n    \"url\": \"https://example.com/plugin.wasm\",\n    \"sha256\": \"beef\",\n    \"name\": \"versionfile\"\n  }\n}\n",
+			after:    "{\n  \"plugin\": {\n    \"url\": \"https://example.com/plugin.wasm\",\n    \"sha256\": \"cafe\",\n    \"name\": \"versionfile\"\n  }\n}\n",
+		},
+		{
+			filename: "config.json",
+			before:   "{\n  \"plugin\": {\n    \"url\": \"https://example.com/plugin.wasm\",\n    \"name\": \"versionfile\"\n  },\n  \"changelog\": {}\n}\n",
+			after:    "{\n  \"plugin\": {\n    \"url\": \"https://example.com/plugin.wasm\",\n    \"name\": \"versionfile\",\n    \"sha256\": \"cafe\"\n  },\n  \"changelog\": {}\n}\n",
+		},
+		{
+			filename: "config.toml",
+			before:   "# The plugin\n[plugin]\nurl = \"https://example.com/plugin.wasm\" # pinned below\nsha256 = 'beef' # updated by changeset\nname = \"versionfile\"\n",
+			after:    "# The plugin\n[plugin]\nurl = \"https://example.com/plugin.wasm\" # pinned below\nsha256 = 'cafe' # updated by changeset\nname = \"versionfile\"\n",
+		},
+		{
+			filename: "config.toml",
+			before:   "[plugin]\nurl = \"https://example.com/plugin.wasm\"\nname = \"versionfile\"\n\n# Other settings\n[changelog]\n",
+			after:    "[plugin]\nurl = \"https://example.com/plugin.wasm\"\nname = \"versionfile\"\nsha256 = \"cafe\"\n\n# Other settings\n[changelog]\n",
+		},
+		{
+			filename: "config.yaml",
+			before:   "# The plugin\nplugin:\n  url: https://example.com/plugin.wasm\n  sha256: \"beef\" # updated by changeset\n  name: versionfile\n",
+			after:    "# The plugin\nplugin:\n  url: https://example.com/plugin.wasm\n  sha256: \"cafe\" # updated by changeset\n  name: versionfile\n",
+		},
+		{
+			filename: "config.yaml",
+			before:   "plugin:\n  url: https://example.com/plugin.wasm\n  name: versionfile # the name\n# Other settings\nchangelog: {}\n",
+			after:    "plugin:\n  url: https://example.com/plugin.wasm\n  name: versionfile # the name\n  sha256: cafe\n# Other settings\nchangelog: {}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filename, func(t *testing.T) {
+			root := t.TempDir()
+			path := filepath.Join(root, CHANGESET_DIRECTORY, test.filename)
+			writeFile(t, path, test.before)
+
+			resolved, err := Resolve(Options{Root: root, Environ: []string{}})
+			require.NoError(t, err)
+			_, err = resolved.PinChecksum(0, "cafe")
+			require.NoError(t, err)
+
+			contents, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, test.after, string(contents))
+		})
+	}
+}
+
+func TestPinChecksumOfTargetOnlyEditsTheTarget(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, CHANGESET_DIRECTORY, "config.yaml")
+	writeFile(t, path, "targets:\n  - name: npm\n    url: https://example.com/npm.wasm\n    versionedFile: package.json\n  # The chart\n  - name: helm\n    url: https://example.com/helm.wasm\n    versionedFile: Chart.yaml\n")
+
+	resolved, err := Resolve(Options{Root: root, Environ: []string{}})
+	require.NoError(t, err)
+	_, err = resolved.PinChecksum(0, "cafe")
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "targets:\n  - name: npm\n    url: https://example.com/npm.wasm\n    versionedFile: package.json\n    sha256: cafe\n  # The chart\n  - name: helm\n    url: https://example.com/helm.wasm\n    versionedFile: Chart.yaml\n", string(contents))
+}
+
+func TestPinChecksumOfABaseOutsideOfTheProject(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.json")
+	writeFile(t, base, `{"plugin": {"name": "versionfile", "url": "https://example.com/plugin.wasm", "sha256": "beef"}}`)
+	root := filepath.Join(dir, "project")
+	writeFile(t, filepath.Join(root, CHANGESET_DIRECTORY, "config.json"), `{"extends": "../../base.json"}`)
+
+	resolved, err := Resolve(Options{Root: root, Environ: []string{}})
+	require.NoError(t, err)
+	_, err = resolved.PinChecksum(0, "cafe")
+	assert.EqualError(t, err, "plugin.sha256 is set by "+base+", which is outside of the project, so its sha256 must be updated by hand")
+
+	contents, err := os.ReadFile(base)
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), `"sha256": "beef"`)
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alex-way/changesets/pkg/minisign"
+)
+
+// The plugin URL schemes which can be fetched
+var SUPPORTED_SCHEMES = []string{"file://", "https://", "exec://"}
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Checks the plugin config makes sense, resolving files against the project root
+func (p Plugin) Validate(root string) error {
+	return p.validate(root, "plugin")
+}
+
+// Validates the plugin, prefixing errors with the path of the plugin within the config
+func (p Plugin) validate(root string, prefix string) error {
+	var errs []error
+
+	if p.URL == "" {
+		errs = append(errs, fmt.Errorf("%s.url is required", prefix))
+	} else if !hasSupportedScheme(p.URL) {
+		errs = append(errs, fmt.Errorf("%s.url %q must start with one of: %s", prefix, p.URL, strings.Join(SUPPORTED_SCHEMES, ", ")))
+	}
+
+	switch p.Type {
+	case "", PLUGIN_TYPE_WASM:
+		if strings.HasPrefix(p.URL, "exec://") && p.Type != "" {
+			errs = append(errs, fmt.Errorf("%s.url %q is an executable, so %s.type must be %s", prefix, p.URL, prefix, PLUGIN_TYPE_EXEC))
+		}
+	case PLUGIN_TYPE_EXEC:
+		if strings.HasPrefix(p.URL, "https://") {
+			errs = append(errs, fmt.Errorf("%s.url %q must be a local file, as executables can't be fetched", prefix, p.URL))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.type %q must be one of: %s, %s", prefix, p.Type, PLUGIN_TYPE_WASM, PLUGIN_TYPE_EXEC))
+	}
+
+	if p.SHA256 != "" && !sha256Pattern.MatchString(p.SHA256) {
+		errs = append(errs, fmt.Errorf("%s.sha256 %q must be 64 lowercase hex characters", prefix, p.SHA256))
+	}
+
+	if p.Limits != nil {
+		errs = append(errs, p.Limits.validate(prefix+".limits")...)
+	}
+
+	if p.VersionedFile == "" {
+		errs = append(errs, fmt.Errorf("%s.versionedFile is required", prefix))
+	} else if _, err := os.Stat(filepath.Join(root, p.VersionedFile)); err != nil {
+		errs = append(errs, fmt.Errorf("%s.versionedFile %q does not exist", prefix, p.VersionedFile))
+	}
+
+	return errors.Join(errs...)
+}
+
+// The most pages a 32-bit WebAssembly memory can have
+const MAX_MEMORY_PAGES uint32 = 65536
+
+func (l Limits) validate(prefix string) []error {
+	var errs []error
+	if l.Timeout != "" {
+		if timeout, err := time.ParseDuration(l.Timeout); err != nil || timeout <= 0 {
+			errs = append(errs, fmt.Errorf("%s.timeout %q must be a positive duration such as 30s", prefix, l.Timeout))
+		}
+	}
+	if l.MemoryPages > MAX_MEMORY_PAGES {
+		errs = append(errs, fmt.Errorf("%s.memoryPages must be at most %d", prefix, MAX_MEMORY_PAGES))
+	}
+	if l.OutputBytes < 0 {
+		errs = append(errs, fmt.Errorf("%s.outputBytes must not be negative", prefix))
+	}
+	return errs
+}
+
+func (s Security) validate() []error {
+	var errs []error
+	for i, key := range s.TrustedKeys {
+		if _, err := minisign.ParsePublicKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("security.trustedKeys[%d]: %w", i, err))
+		}
+	}
+	if s.Strict && len(s.TrustedKeys) == 0 {
+		errs = append(errs, errors.New("security.strict requires at least one of security.trustedKeys to verify signatures with"))
+	}
+	return errs
+}
+
+func (n Network) validate() []error {
+	var errs []error
+	froms := make([]string, 0, len(n.Mirrors))
+	for from := range n.Mirrors {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	for _, from := range froms {
+		to := n.Mirrors[from]
+		if !strings.HasPrefix(from, "https://") {
+			errs = append(errs, fmt.Errorf("network.mirrors key %q must be the start of an https:// URL", from))
+		}
+		if !strings.HasPrefix(to, "https://") {
+			errs = append(errs, fmt.Errorf("network.mirrors[%q] %q must be an https:// URL", from, to))
+		}
+	}
+	if n.Retries < 0 {
+		errs = append(errs, errors.New("network.retries must not be negative"))
+	}
+	return errs
+}
+
+func validatePatterns(field string, patterns []string) []error {
+	var errs []error
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s pattern %q is invalid: %w", field, pattern, err))
+		}
+	}
+	return errs
+}
+
+func hasSupportedScheme(url string) bool {
+	for _, scheme := range SUPPORTED_SCHEMES {
+		if strings.HasPrefix(url, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks the config makes sense, resolving files against the project root
+func (c Config) Validate(root string) error {
+	var errs []error
+	if len(c.Targets) == 0 {
+		if err := c.Plugin.Validate(root); err != nil {
+			errs = append(errs, err)
+		}
+	} else {
+		errs = append(errs, c.validateTargets(root)...)
+	}
+
+	if _, err := c.Vocabulary(); err != nil {
+		errs = append(errs, fmt.Errorf("bumps: %w", err))
+	}
+
+	errs = append(errs, validatePatterns("ignore", c.Ignore)...)
+	if c.Packages != nil {
+		errs = append(errs, validatePatterns("packages.ignore", c.Packages.Ignore)...)
+		errs = append(errs, validatePatterns("packages.private", c.Packages.Private)...)
+	}
+
+	if c.Security != nil {
+		errs = append(errs, c.Security.validate()...)
+	}
+	if c.Network != nil {
+		errs = append(errs, c.Network.validate()...)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
+	}
+	return nil
+}
+
+func (c Config) validateTargets(root string) []error {
+	var errs []error
+	if c.Plugin.Name != "" || c.Plugin.URL != "" || c.Plugin.SHA256 != "" {
+		errs = append(errs, errors.New("plugin and targets can't both be set, move the plugin into targets"))
+	}
+
+	sources := 0
+	for i, target := range c.Targets {
+		if err := target.validate(root, fmt.Sprintf("targets[%d]", i)); err != nil {
+			errs = append(errs, err)
+		}
+		if target.Source {
+			sources++
+		}
+	}
+	if sources > 1 {
+		errs = append(errs, fmt.Errorf("only one target can be the source, found %d", sources))
+	}
+	return errs
+}
@@ -0,0 +1,88 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Returned when a plugin has to be downloaded, but downloads are disabled by network.offline
+type OfflineError struct {
+	Plugin string
+	URL    string
+}
+
+func (e *OfflineError) Error() string {
+	return fmt.Sprintf("plugin %s not cached, run `changeset plugin install`", e.Plugin)
+}
+
+// The delay before the first retry of a failed download, which doubles with each retry
+var retryBackoff = 500 * time.Millisecond
+
+// The transport downloads are made with, which is cloned to set its proxy
+var baseTransport = http.DefaultTransport.(*http.Transport)
+
+// Returns a client which uses the proxy set by HTTPS_PROXY and NO_PROXY. Unlike http.ProxyFromEnvironment, the
+// environment is read whenever a client is created rather than once per process
+func httpClient() *http.Client {
+	proxy := httpproxy.FromEnvironment().ProxyFunc()
+	transport := baseTransport.Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+	return &http.Client{Transport: transport}
+}
+
+// Whether a failed download might succeed when retried
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// Downloads the URL from its mirror, retrying with exponential backoff when the server fails or can't be reached
+func (r *Runner) download(ctx context.Context, uri string) (*http.Response, error) {
+	if r.Network.IsOffline() {
+		return nil, &OfflineError{Plugin: r.Plugin.Name, URL: uri}
+	}
+	uri = r.Network.Mirror(uri)
+
+	client := httpClient()
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+		if err != nil {
+			return nil, fmt.Errorf("http.Get: %s %w", uri, err)
+		}
+		resp, err := client.Do(req)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		if attempt >= r.Network.RetryCount() || !retryable(resp, err) {
+			if err != nil {
+				return nil, fmt.Errorf("http.Get: %s %w", uri, err)
+			}
+			resp.Body.Close()
+			return nil, fmt.Errorf("http.Get: %s %s", uri, resp.Status)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		slog.Debug("download failed, retrying", "url", uri, "attempt", attempt+1, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,173 @@
+package wasm
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/plugin"
+)
+
+// Starts an HTTPS server which is trusted by downloads for the duration of the test
+func newPluginServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	transport, backoff := baseTransport, retryBackoff
+	baseTransport, retryBackoff = server.Client().Transport.(*http.Transport), time.Millisecond
+	t.Cleanup(func() { baseTransport, retryBackoff = transport, backoff })
+	return server
+}
+
+// Serves the contents, after failing the first requests with the status
+func failingHandler(contents []byte, failures int32, status int, requests *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write(contents)
+	}
+}
+
+func TestDownloadsAreRetriedWithBackoff(t *testing.T) {
+	var requests atomic.Int32
+	server := newPluginServer(t, failingHandler([]byte("plugin"), 2, http.StatusServiceUnavailable, &requests))
+	runner := &Runner{Plugin: config.Plugin{Name: "test", URL: server.URL + "/plugin.wasm"}}
+
+	contents, sum, _, err := runner.fetch(context.Background(), runner.Plugin.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "plugin", string(contents))
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte("plugin"))), sum)
+	assert.EqualValues(t, 3, requests.Load())
+}
+
+func TestDownloadsGiveUp(t *testing.T) {
+	var requests atomic.Int32
+	server := newPluginServer(t, failingHandler(nil, 100, http.StatusInternalServerError, &requests))
+	runner := &Runner{Plugin: config.Plugin{Name: "test", URL: server.URL + "/plugin.wasm"}, Network: &config.Network{Retries: 2}}
+
+	_, _, _, err := runner.fetch(context.Background(), runner.Plugin.URL)
+	assert.EqualError(t, err, "http.Get: "+server.URL+"/plugin.wasm 500 Internal Server Error")
+	assert.EqualValues(t, 3, requests.Load())
+
+	// Requests which can't succeed aren't retried
+	requests.Store(0)
+	server.Config.Handler = failingHandler(nil, 100, http.StatusNotFound, &requests)
+	_, _, _, err = runner.fetch(context.Background(), runner.Plugin.URL)
+	assert.EqualError(t, err, "http.Get: "+server.URL+"/plugin.wasm 404 Not Found")
+	assert.EqualValues(t, 1, requests.Load())
+}
+
+func TestDownloadsUseTheMirror(t *testing.T) {
+	var path string
+	server := newPluginServer(t, func(w http.ResponseWriter, req *http.Request) {
+		path = req.URL.Path
+		w.Write([]byte("plugin"))
+	})
+	runner := &Runner{
+		Plugin: config.Plugin{Name: "test", URL: "https://github.com/org/repo/releases/download/1.0.0/plugin.wasm"},
+		Network: &config.Network{Mirrors: map[string]string{
+			"https://github.com/":          "https://unused.example.com/",
+			"https://github.com/org/repo/": server.URL + "/mirror/",
+		}},
+	}
+
+	_, _, resolved, err := runner.fetch(context.Background(), runner.Plugin.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "/mirror/releases/download/1.0.0/plugin.wasm", path)
+	assert.Equal(t, server.URL+"/mirror/releases/download/1.0.0/plugin.wasm", resolved)
+}
+
+func TestDownloadsUseTheProxy(t *testing.T) {
+	server := newPluginServer(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("plugin"))
+	})
+
+	// Tunnels every CONNECT request to the plugin server, whichever host it's for
+	var mu sync.Mutex
+	var tunnelled []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodConnect {
+			http.Error(w, "expected CONNECT", http.StatusMethodNotAllowed)
+			return
+		}
+		mu.Lock()
+		tunnelled = append(tunnelled, req.Host)
+		mu.Unlock()
+
+		upstream, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(proxy.Close)
+	t.Setenv("HTTPS_PROXY", proxy.URL)
+
+	runner := &Runner{Plugin: config.Plugin{Name: "test", URL: "https://plugins.example.com/plugin.wasm"}}
+	contents, _, _, err := runner.fetch(context.Background(), runner.Plugin.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "plugin", string(contents))
+	assert.Equal(t, []string{"plugins.example.com:443"}, tunnelled)
+}
+
+func TestOfflineOnlyUsesTheCache(t *testing.T) {
+	// Built first, as a new home directory has an empty build cache
+	wmod, err := os.ReadFile(buildPlugin(t))
+	require.NoError(t, err)
+	t.Setenv("HOME", t.TempDir())
+	var requests atomic.Int32
+	server := newPluginServer(t, failingHandler(wmod, 0, 0, &requests))
+
+	_plugin := config.Plugin{
+		Name:          "test",
+		URL:           server.URL + "/plugin.wasm",
+		SHA256:        fmt.Sprintf("%x", sha256.Sum256(wmod)),
+		VersionedFile: "VERSION",
+	}
+	root := newProject(t)
+	offline := &Runner{Plugin: _plugin, Root: root, Registry: NewRegistry(), Network: &config.Network{Offline: true}}
+	defer offline.Registry.Close(context.Background())
+	client := plugin.NewVersionGetterSetterServiceClient(offline)
+
+	_, err = client.Request(context.Background(), getVersionRequest())
+	assert.EqualError(t, err, "plugin test not cached, run `changeset plugin install`")
+	_, err = offline.Install(context.Background(), false)
+	assert.EqualError(t, err, "plugin test can't be installed while offline")
+	assert.EqualValues(t, 0, requests.Load())
+
+	_, err = (&Runner{Plugin: _plugin, Root: root}).Install(context.Background(), false)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, requests.Load())
+
+	resp, err := client.Request(context.Background(), getVersionRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", resp.GetGetVersion().Version)
+	assert.EqualValues(t, 1, requests.Load())
+}
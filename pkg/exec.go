@@ -0,0 +1,225 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/plugin"
+)
+
+// How long an executable plugin has to exit once it's been killed or has closed its output, before it's abandoned
+const EXEC_WAIT_DELAY time.Duration = time.Second
+
+// Runs a local executable or script as a plugin, speaking the same protocol as WASM plugins: the method is passed as
+// the first argument, the encoded RequestMessage on stdin and the encoded Response on stdout. Executables aren't
+// sandboxed, so they can access anything the user running changeset can
+type ExecRunner struct {
+	Plugin config.Plugin
+	// The project root, which is the working directory of the plugin and used to resolve relative paths
+	Root string
+	// Where the handshake of the plugin is kept between requests, defaulting to DefaultRegistry
+	Registry *Registry
+	// Whether the plugin must be pinned and signed, verifying nothing when nil
+	Security *config.Security
+	// Where the logs of the plugin are forwarded, defaulting to slog.Default
+	Logger *slog.Logger
+	// The lockfile, whose checksum is enforced when the plugin isn't pinned in the config. Nothing is enforced when empty
+	Lock string
+}
+
+func (r *ExecRunner) root() string {
+	if r.Root == "" {
+		return "."
+	}
+	return r.Root
+}
+
+func (r *ExecRunner) registry() *Registry {
+	if r.Registry == nil {
+		return DefaultRegistry
+	}
+	return r.Registry
+}
+
+func (r *ExecRunner) logger() *slog.Logger {
+	if r.Logger == nil {
+		return slog.Default()
+	}
+	return r.Logger
+}
+
+// Returns the path of the executable. Relative paths are resolved against the project root, while bare names such as
+// exec://my-plugin are looked up on the PATH
+func (r *ExecRunner) path() (string, error) {
+	name := strings.TrimPrefix(strings.TrimPrefix(r.Plugin.URL, "exec://"), "file://")
+	if !strings.ContainsRune(name, '/') {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			return "", fmt.Errorf("plugin %s: %w", r.Plugin.Name, err)
+		}
+		return path, nil
+	}
+	return filepath.Abs(r.resolve(name))
+}
+
+// Resolves a path relative to the project root
+func (r *ExecRunner) resolve(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(r.root(), name)
+}
+
+// Returns the sha256 of the executable, ignoring any checksum set in the config
+func (r *ExecRunner) Checksum(ctx context.Context) (string, error) {
+	path, err := r.path()
+	if err != nil {
+		return "", err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("os.ReadFile: %s %w", path, err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(contents)), nil
+}
+
+// Checks the executable against its pinned checksum and performs the handshake, once per process
+func (r *ExecRunner) load(ctx context.Context, _limits limits) (string, *runtimeAndCode, error) {
+	path, err := r.path()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := checkPinned(r.Plugin, r.Security); err != nil {
+		return "", nil, err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("os.ReadFile: %s %w", path, err)
+	}
+	actual_sha := fmt.Sprintf("%x", sha256.Sum256(contents))
+	if err := checkChecksum(r.Plugin, r.Lock, actual_sha); err != nil {
+		return "", nil, err
+	}
+
+	loaded, err := r.registry().load(fmt.Sprintf("exec %s %s", path, actual_sha), func() (*runtimeAndCode, error) {
+		err := verifySignature(r.Plugin, r.Security, contents, func(uri string) ([]byte, error) {
+			return os.ReadFile(r.resolve(strings.TrimPrefix(uri, "file://")))
+		})
+		if err != nil {
+			return nil, err
+		}
+		if r.Plugin.SHA256 == "" {
+			slog.Warn("calculated the sha256 of the plugin executable. Set this value in your config file to pin it", "sha256", actual_sha)
+		}
+		info, err := handshake(r.Plugin.Name, func(req *plugin.RequestMessage, resp *plugin.Response) error {
+			return r.run(ctx, path, plugin.VersionGetterSetterService_Request_FullMethodName, req, resp, _limits)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &runtimeAndCode{info: info}, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return path, loaded, nil
+}
+
+// Returns what the plugin reported about itself when it was loaded
+func (r *ExecRunner) Info(ctx context.Context) (*plugin.GetInfoResponse, error) {
+	_limits, err := pluginLimits(r.Plugin)
+	if err != nil {
+		return nil, err
+	}
+	_, loaded, err := r.load(ctx, _limits)
+	if err != nil {
+		return nil, err
+	}
+	return loaded.info, nil
+}
+
+func (r *ExecRunner) Invoke(ctx context.Context, method string, args any, reply any, opts ...grpc.CallOption) error {
+	req, ok := args.(protoreflect.ProtoMessage)
+	if !ok {
+		return status.Error(codes.InvalidArgument, "args isn't a protoreflect.ProtoMessage")
+	}
+
+	_limits, err := pluginLimits(r.Plugin)
+	if err != nil {
+		return err
+	}
+
+	path, loaded, err := r.load(ctx, _limits)
+	if err != nil {
+		return err
+	}
+
+	if genReq, ok := req.(*plugin.RequestMessage); ok {
+		if err := checkCapability(r.Plugin.Name, loaded.info, genReq); err != nil {
+			return err
+		}
+	}
+
+	return r.run(ctx, path, method, req, reply, _limits)
+}
+
+// Runs the executable for a single request
+func (r *ExecRunner) run(ctx context.Context, path string, method string, req protoreflect.ProtoMessage, reply any, _limits limits) error {
+	stdinBlob, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode codegen request: %w", err)
+	}
+
+	stderr := limitedBuffer{max: _limits.outputBytes}
+	stdout := limitedBuffer{max: _limits.outputBytes}
+
+	run_ctx, cancel := context.WithTimeout(ctx, _limits.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(run_ctx, path, method)
+	cmd.Dir = r.root()
+	cmd.Stdin = bytes.NewReader(stdinBlob)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = EXEC_WAIT_DELAY
+
+	err = cmd.Run()
+	if err != nil && errors.Is(run_ctx.Err(), context.DeadlineExceeded) {
+		return &LimitError{Plugin: r.Plugin.Name, Limit: LIMIT_TIMEOUT, Value: _limits.timeout.String()}
+	}
+	if stdout.exceeded || stderr.exceeded {
+		return &LimitError{Plugin: r.Plugin.Name, Limit: LIMIT_OUTPUT, Value: fmt.Sprintf("%d bytes", _limits.outputBytes)}
+	}
+	unstructured := forwardLogs(ctx, r.logger(), r.Plugin.Name, stderr.Bytes())
+	if cerr := checkError(err, unstructured); cerr != nil {
+		return cerr
+	}
+	logUnstructured(ctx, r.logger(), r.Plugin.Name, unstructured)
+
+	resp, ok := reply.(protoreflect.ProtoMessage)
+	if !ok {
+		return fmt.Errorf("reply isn't a GenerateResponse")
+	}
+
+	return proto.Unmarshal(stdout.Bytes(), resp)
+}
+
+func (r *ExecRunner) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
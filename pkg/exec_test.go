@@ -0,0 +1,94 @@
+package wasm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/plugin"
+)
+
+func newExecRunner(t *testing.T, root string, _plugin config.Plugin) *ExecRunner {
+	registry := NewRegistry()
+	t.Cleanup(func() { registry.Close(context.Background()) })
+	_plugin.Name = "test"
+	_plugin.VersionedFile = "VERSION"
+	if _plugin.URL == "" {
+		_plugin.URL = "exec://" + buildNativePlugin(t)
+	}
+	return &ExecRunner{Plugin: _plugin, Root: root, Registry: registry}
+}
+
+func TestNewClientSelectsTheRunner(t *testing.T) {
+	assert.IsType(t, &Runner{}, NewClient(config.Plugin{URL: "https://example.com/plugin.wasm"}, ".", config.Config{}))
+	assert.IsType(t, &ExecRunner{}, NewClient(config.Plugin{URL: "exec://plugin.py"}, ".", config.Config{}))
+	assert.IsType(t, &ExecRunner{}, NewClient(config.Plugin{URL: "file://plugin.py", Type: config.PLUGIN_TYPE_EXEC}, ".", config.Config{}))
+}
+
+func TestExecPluginCanReadAndWriteTheVersionedFile(t *testing.T) {
+	root := newProject(t)
+	client := plugin.NewVersionGetterSetterServiceClient(newExecRunner(t, root, config.Plugin{}))
+
+	resp, err := client.Request(context.Background(), getVersionRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", resp.GetGetVersion().Version)
+
+	_, err = client.Request(context.Background(), &plugin.RequestMessage{Request: &plugin.RequestMessage_SetVersion{
+		SetVersion: &plugin.SetVersionRequest{FilePath: "VERSION", Version: "2.0.0"},
+	}})
+	require.NoError(t, err)
+	contents, err := os.ReadFile(filepath.Join(root, "VERSION"))
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0\n", string(contents))
+}
+
+func TestExecPluginResolvesRelativePaths(t *testing.T) {
+	root := newProject(t)
+	contents, err := os.ReadFile(buildNativePlugin(t))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "plugin"), contents, 0755))
+
+	runner := newExecRunner(t, root, config.Plugin{URL: "exec://./plugin"})
+	info, err := runner.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, plugin.PROTOCOL_VERSION, info.ProtocolVersion)
+}
+
+func TestExecPluginChecksumIsPinned(t *testing.T) {
+	runner := newExecRunner(t, newProject(t), config.Plugin{SHA256: "0000000000000000000000000000000000000000000000000000000000000000"})
+	_, err := plugin.NewVersionGetterSetterServiceClient(runner).Request(context.Background(), getVersionRequest())
+	assert.ErrorContains(t, err, "invalid checksum: expected 0000000000000000000000000000000000000000000000000000000000000000")
+
+	sum, err := runner.Checksum(context.Background())
+	require.NoError(t, err)
+	runner.Plugin.SHA256 = sum
+	_, err = plugin.NewVersionGetterSetterServiceClient(runner).Request(context.Background(), getVersionRequest())
+	assert.NoError(t, err)
+}
+
+func TestExecPluginErrorsAndLimits(t *testing.T) {
+	root := newProject(t)
+	runner := newExecRunner(t, root, config.Plugin{Limits: &config.Limits{Timeout: "500ms", OutputBytes: 1024}})
+
+	_, err := getVersionWith(t, runner, map[string]interface{}{"read": "missing.txt"})
+	assert.EqualError(t, err, "open missing.txt: no such file or directory\n")
+
+	_, err = getVersionWith(t, runner, map[string]interface{}{"loop": true})
+	assert.EqualError(t, err, "plugin test exceeded its timeout limit of 500ms")
+
+	_, err = getVersionWith(t, runner, map[string]interface{}{"spam": 4096})
+	assert.EqualError(t, err, "plugin test exceeded its output limit of 1024 bytes")
+}
+
+func getVersionWith(t *testing.T, runner *ExecRunner, settings map[string]interface{}) (*plugin.Response, error) {
+	value, err := plugin.NewSettings(settings)
+	require.NoError(t, err)
+	return plugin.NewVersionGetterSetterServiceClient(runner).Request(context.Background(), &plugin.RequestMessage{Request: &plugin.RequestMessage_GetVersion{
+		GetVersion: &plugin.GetVersionRequest{FilePath: "VERSION", Settings: value},
+	}})
+}
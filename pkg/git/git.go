@@ -0,0 +1,159 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Returned when git is unavailable or the working directory is not a git checkout
+var ErrNotRepository = errors.New("not a git repository")
+
+// Returned when the file has not been committed yet
+var ErrNoCommit = errors.New("no commit found for file")
+
+// Matches squash merge subjects such as "Add a thing (#123)"
+var squashPattern = regexp.MustCompile(`\(#(\d+)\)\s*$`)
+
+// Matches merge commit subjects such as "Merge pull request #123 from user/branch"
+var mergePattern = regexp.MustCompile(`^Merge pull request #(\d+)`)
+
+const fieldSeparator = "\x1f"
+
+type Commit struct {
+	Hash        string
+	Subject     string
+	AuthorName  string
+	AuthorEmail string
+	// The subject of the merge commit which brought the commit into HEAD, if it was merged
+	MergeSubject string
+}
+
+// Returns the abbreviated commit hash
+func (c Commit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// Returns the pull request number parsed from the subject of the commit or of the merge which brought it in, or 0 if
+// there isn't one
+func (c Commit) PullRequest() int {
+	if number := ParsePullRequest(c.Subject); number != 0 {
+		return number
+	}
+	return ParsePullRequest(c.MergeSubject)
+}
+
+// Parses a pull request number from a merge or squash commit subject, returning 0 if none is found
+func ParsePullRequest(subject string) int {
+	for _, pattern := range []*regexp.Regexp{squashPattern, mergePattern} {
+		match := pattern.FindStringSubmatch(subject)
+		if match == nil {
+			continue
+		}
+		number, err := strconv.Atoi(match[1])
+		if err != nil {
+			return 0
+		}
+		return number
+	}
+	return 0
+}
+
+func run(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.Is(err, exec.ErrNotFound) || (errors.As(err, &exitErr) && strings.Contains(stderr.String(), "not a git repository")) {
+			return "", ErrNotRepository
+		}
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// Looks up the commit which added the file at the given path using the local repository only
+func FindCommit(path string) (Commit, error) {
+	format := strings.Join([]string{"%H", "%s", "%an", "%ae"}, fieldSeparator)
+	output, err := run(filepath.Dir(path), "log", "--diff-filter=A", "-n", "1", "--format="+format, "--", filepath.Base(path))
+	if err != nil {
+		return Commit{}, err
+	}
+
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return Commit{}, ErrNoCommit
+	}
+
+	parts := strings.Split(output, fieldSeparator)
+	if len(parts) != 4 {
+		return Commit{}, fmt.Errorf("unexpected git log output: %q", output)
+	}
+
+	commit := Commit{Hash: parts[0], Subject: parts[1], AuthorName: parts[2], AuthorEmail: parts[3]}
+	commit.MergeSubject, err = findMerge(filepath.Dir(path), commit.Hash)
+	if err != nil {
+		return Commit{}, err
+	}
+	return commit, nil
+}
+
+// Whether the commit is an ancestor of, or is, the other commit
+func isAncestor(dir string, commit string, of string) (bool, error) {
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", commit, of)
+	cmd.Dir = dir
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Returns the subject of the merge which brought the commit into HEAD: the first merge along the first-parent line of
+// HEAD whose merged branch contains the commit. Returns nothing when the commit was made on that line directly
+func findMerge(dir string, hash string) (string, error) {
+	line, err := run(dir, "rev-list", "--first-parent", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	for _, commit := range strings.Fields(line) {
+		if commit == hash {
+			return "", nil
+		}
+	}
+
+	format := strings.Join([]string{"%P", "%s"}, fieldSeparator)
+	output, err := run(dir, "log", "--first-parent", "--merges", "--reverse", "--format="+format, hash+"..HEAD")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		parents, subject, ok := strings.Cut(line, fieldSeparator)
+		if !ok {
+			continue
+		}
+		for _, parent := range strings.Fields(parents)[1:] {
+			merged, err := isAncestor(dir, hash, parent)
+			if err != nil {
+				return "", err
+			}
+			if merged {
+				return subject, nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,99 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePullRequest(t *testing.T) {
+	assert.Equal(t, 123, ParsePullRequest("Add a new feature (#123)"))
+	assert.Equal(t, 45, ParsePullRequest("Merge pull request #45 from alex-way/feature"))
+	assert.Equal(t, 0, ParsePullRequest("Fix issue #12 in the parser"))
+	assert.Equal(t, 0, ParsePullRequest("Plain commit"))
+}
+
+func TestShortHash(t *testing.T) {
+	commit := Commit{Hash: "9c897781b2d3e4f5"}
+	assert.Equal(t, "9c89778", commit.ShortHash())
+}
+
+// Runs git in the directory, failing the test on error
+func gitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func TestFindCommitReadsThePullRequestOfTheMerge(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	gitIn(t, dir, "init", "-q", "-b", "main")
+	gitIn(t, dir, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	gitIn(t, dir, "checkout", "-q", "-b", "feature")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "change.md"), []byte("# Added a thing\n"), 0644))
+	gitIn(t, dir, "add", "change.md")
+	gitIn(t, dir, "commit", "-q", "-m", "Add a thing")
+	gitIn(t, dir, "checkout", "-q", "main")
+	gitIn(t, dir, "merge", "-q", "--no-ff", "-m", "Merge pull request #7 from alex-way/feature", "feature")
+	gitIn(t, dir, "commit", "-q", "--allow-empty", "-m", "Merge pull request #8 from alex-way/later")
+
+	commit, err := FindCommit(filepath.Join(dir, "change.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "Add a thing", commit.Subject)
+	assert.Equal(t, "Merge pull request #7 from alex-way/feature", commit.MergeSubject)
+	assert.Equal(t, 7, commit.PullRequest())
+}
+
+func TestFindCommitIgnoresUnrelatedMergesOfDirectCommits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	gitIn(t, dir, "init", "-q", "-b", "main")
+	gitIn(t, dir, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	gitIn(t, dir, "checkout", "-q", "-b", "other")
+	gitIn(t, dir, "commit", "-q", "--allow-empty", "-m", "Something else")
+	gitIn(t, dir, "checkout", "-q", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "change.md"), []byte("# Added a thing\n"), 0644))
+	gitIn(t, dir, "add", "change.md")
+	gitIn(t, dir, "commit", "-q", "-m", "Add a thing")
+	gitIn(t, dir, "merge", "-q", "--no-ff", "-m", "Merge pull request #99 from x/other", "other")
+
+	commit, err := FindCommit(filepath.Join(dir, "change.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "", commit.MergeSubject)
+	assert.Equal(t, 0, commit.PullRequest())
+}
+
+func TestFindCommitReadsThePullRequestOfABranchWhichMergedMain(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	gitIn(t, dir, "init", "-q", "-b", "main")
+	gitIn(t, dir, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	gitIn(t, dir, "checkout", "-q", "-b", "feature")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "change.md"), []byte("# Added a thing\n"), 0644))
+	gitIn(t, dir, "add", "change.md")
+	gitIn(t, dir, "commit", "-q", "-m", "Add a thing")
+	gitIn(t, dir, "checkout", "-q", "main")
+	gitIn(t, dir, "commit", "-q", "--allow-empty", "-m", "Something else")
+	gitIn(t, dir, "checkout", "-q", "feature")
+	gitIn(t, dir, "merge", "-q", "--no-ff", "-m", "Merge branch 'main' into feature", "main")
+	gitIn(t, dir, "checkout", "-q", "main")
+	gitIn(t, dir, "merge", "-q", "--no-ff", "-m", "Merge pull request #7 from alex-way/feature", "feature")
+
+	commit, err := FindCommit(filepath.Join(dir, "change.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "Merge pull request #7 from alex-way/feature", commit.MergeSubject)
+	assert.Equal(t, 7, commit.PullRequest())
+}
@@ -0,0 +1,24 @@
+// Signs messages the way minisign does, so that tests can check signatures are verified. Changeset itself only ever
+// verifies signatures
+package minisigntest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Signs the message in the prehashed format, returning the contents of a .minisig file
+func Sign(key ed25519.PrivateKey, id [8]byte, message []byte, trustedComment string) []byte {
+	hash := blake2b.Sum512(message)
+	signature := ed25519.Sign(key, hash[:])
+	global := ed25519.Sign(key, bytes.Join([][]byte{signature, []byte(trustedComment)}, nil))
+
+	line := bytes.Join([][]byte{[]byte("ED"), id[:], signature}, nil)
+	return []byte("untrusted comment: signature from changeset\n" +
+		base64.StdEncoding.EncodeToString(line) + "\n" +
+		"trusted comment: " + trustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
@@ -0,0 +1,173 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental"
+
+	"github.com/alex-way/changesets/pkg/config"
+)
+
+// The limits applied to plugins which don't configure their own
+const DEFAULT_TIMEOUT time.Duration = 30 * time.Second
+const DEFAULT_MEMORY_PAGES uint32 = 4096
+const DEFAULT_OUTPUT_BYTES int = 16 << 20
+
+const WASM_PAGE_SIZE uint64 = 65536
+
+// The names of the limits reported by LimitError
+const LIMIT_TIMEOUT string = "timeout"
+const LIMIT_MEMORY string = "memory"
+const LIMIT_OUTPUT string = "output"
+
+// Returned when a plugin exceeds one of its limits
+type LimitError struct {
+	Plugin string
+	// One of LIMIT_TIMEOUT, LIMIT_MEMORY or LIMIT_OUTPUT
+	Limit string
+	// The limit which was exceeded, e.g. 30s
+	Value string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("plugin %s exceeded its %s limit of %s", e.Plugin, e.Limit, e.Value)
+}
+
+type limits struct {
+	timeout     time.Duration
+	memoryPages uint32
+	outputBytes int
+}
+
+// Returns the limits of the plugin, falling back to the defaults for any which aren't configured
+func pluginLimits(_plugin config.Plugin) (limits, error) {
+	resolved := limits{timeout: DEFAULT_TIMEOUT, memoryPages: DEFAULT_MEMORY_PAGES, outputBytes: DEFAULT_OUTPUT_BYTES}
+	configured := _plugin.Limits
+	if configured == nil {
+		return resolved, nil
+	}
+
+	if configured.Timeout != "" {
+		timeout, err := time.ParseDuration(configured.Timeout)
+		if err != nil {
+			return limits{}, fmt.Errorf("invalid timeout for plugin %s: %w", _plugin.Name, err)
+		}
+		resolved.timeout = timeout
+	}
+	if configured.MemoryPages != 0 {
+		resolved.memoryPages = configured.MemoryPages
+	}
+	if configured.OutputBytes != 0 {
+		resolved.outputBytes = configured.OutputBytes
+	}
+	return resolved, nil
+}
+
+func (l limits) memoryError(plugin string) *LimitError {
+	return &LimitError{Plugin: plugin, Limit: LIMIT_MEMORY, Value: fmt.Sprintf("%d pages (%d MiB)", l.memoryPages, uint64(l.memoryPages)*WASM_PAGE_SIZE>>20)}
+}
+
+// A buffer which stops accepting writes once it holds the maximum number of bytes
+type limitedBuffer struct {
+	buffer   []byte
+	max      int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if len(b.buffer)+len(p) > b.max {
+		b.exceeded = true
+		return 0, fmt.Errorf("output limit of %d bytes exceeded", b.max)
+	}
+	b.buffer = append(b.buffer, p...)
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buffer
+}
+
+func (b *limitedBuffer) String() string {
+	return string(b.buffer)
+}
+
+// Records how the memory of a plugin grows. The runtime refuses to grow the memory past its limit without asking the
+// allocator, so a failed plugin is told to have run out of memory by how close to the limit its memory was left
+type memoryWatcher struct {
+	// The configured limit in bytes
+	limit uint64
+	mu    sync.Mutex
+	// The size of the memory, and how much it last grew by
+	size   uint64
+	growth uint64
+}
+
+func newMemoryWatcher(_limits limits) *memoryWatcher {
+	return &memoryWatcher{limit: uint64(_limits.memoryPages) * WASM_PAGE_SIZE}
+}
+
+func (w *memoryWatcher) Allocate(cap, max uint64) experimental.LinearMemory {
+	return &watchedMemory{watcher: w}
+}
+
+// Whether the memory was too close to the limit to grow by as much as it last did
+func (w *memoryWatcher) exhausted() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size+w.growth > w.limit
+}
+
+type watchedMemory struct {
+	watcher *memoryWatcher
+	buffer  []byte
+}
+
+func (m *watchedMemory) Reallocate(size uint64) []byte {
+	m.watcher.mu.Lock()
+	if size > m.watcher.size {
+		m.watcher.growth = size - m.watcher.size
+		m.watcher.size = size
+	}
+	m.watcher.mu.Unlock()
+
+	if size <= uint64(cap(m.buffer)) {
+		m.buffer = m.buffer[:size]
+		return m.buffer
+	}
+	buffer := make([]byte, size)
+	copy(buffer, m.buffer)
+	m.buffer = buffer
+	return m.buffer
+}
+
+func (m *watchedMemory) Free() {
+	m.buffer = nil
+}
+
+// Returns the number of pages the memory of the module starts with. The module is compiled by a runtime without a
+// memory limit, since one with the limit refuses modules which start with more memory than it
+func minimumMemoryPages(ctx context.Context, wmod []byte) uint32 {
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+	defer rt.Close(ctx)
+	code, err := rt.CompileModule(ctx, wmod)
+	if err != nil {
+		return 0
+	}
+
+	memories := code.ImportedMemories()
+	for _, memory := range code.ExportedMemories() {
+		memories = append(memories, memory)
+	}
+
+	var pages uint32
+	for _, memory := range memories {
+		if memory.Min() > pages {
+			pages = memory.Min()
+		}
+	}
+	return pages
+}
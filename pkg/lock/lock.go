@@ -0,0 +1,64 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// The name of the lockfile within the changeset directory
+const LOCKFILE_NAME string = "plugins.lock"
+
+// A plugin as it was installed
+type Plugin struct {
+	Name string `json:"name"`
+	// The URL as configured, rather than the mirror or redirect it was fetched from, which differ between machines
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// Records the plugins installed for a project, so that every machine runs exactly the same plugins
+type Lock struct {
+	Plugins []Plugin `json:"plugins"`
+}
+
+// Reads the lockfile, returning an empty lock when it doesn't exist
+func Read(path string) (Lock, error) {
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Lock{}, nil
+	}
+	if err != nil {
+		return Lock{}, err
+	}
+
+	var lock Lock
+	if err := json.Unmarshal(contents, &lock); err != nil {
+		return Lock{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return lock, nil
+}
+
+// Writes the lockfile with the plugins sorted by name, so that it only changes when the plugins do
+func (l Lock) Write(path string) error {
+	sort.SliceStable(l.Plugins, func(i, j int) bool {
+		return l.Plugins[i].Name < l.Plugins[j].Name
+	})
+	contents, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(contents, '\n'), 0644)
+}
+
+// Returns the locked plugin with the name and URL
+func (l Lock) Find(name string, url string) (Plugin, bool) {
+	for _, _plugin := range l.Plugins {
+		if _plugin.Name == name && _plugin.URL == url {
+			return _plugin, true
+		}
+	}
+	return Plugin{}, false
+}
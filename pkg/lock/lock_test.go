@@ -0,0 +1,39 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadMissingLock(t *testing.T) {
+	_lock, err := Read(filepath.Join(t.TempDir(), LOCKFILE_NAME))
+	require.NoError(t, err)
+	assert.Empty(t, _lock.Plugins)
+}
+
+func TestWriteAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LOCKFILE_NAME)
+	_lock := Lock{Plugins: []Plugin{
+		{Name: "npm", URL: "https://example.com/npm.wasm", SHA256: "b"},
+		{Name: "helm", URL: "file://helm.wasm", SHA256: "a"},
+	}}
+	require.NoError(t, _lock.Write(path))
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), `"name": "helm"`)
+
+	read, err := Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"helm", "npm"}, []string{read.Plugins[0].Name, read.Plugins[1].Name})
+
+	found, ok := read.Find("npm", "https://example.com/npm.wasm")
+	assert.True(t, ok)
+	assert.Equal(t, "b", found.SHA256)
+	_, ok = read.Find("npm", "https://example.com/other.wasm")
+	assert.False(t, ok)
+}
@@ -0,0 +1,70 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// Plugins log by writing JSON objects to stderr, one per line, as written by slog.NewJSONHandler. Each needs a "msg",
+// and may have a "level" of DEBUG, INFO, WARN or ERROR. Any other fields are kept as attributes of the record
+func parseLog(line string) (slog.Level, string, []any, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return 0, "", nil, false
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return 0, "", nil, false
+	}
+	msg, ok := fields["msg"].(string)
+	if !ok {
+		return 0, "", nil, false
+	}
+
+	level := slog.LevelInfo
+	if name, ok := fields["level"].(string); ok {
+		if err := level.UnmarshalText([]byte(name)); err != nil {
+			level = slog.LevelInfo
+		}
+	}
+
+	// The plugin attribute is set by the runner, so plugins can't impersonate each other
+	for _, key := range []string{"msg", "level", "time", "plugin"} {
+		delete(fields, key)
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var attrs []any
+	for _, key := range keys {
+		attrs = append(attrs, slog.Any(key, fields[key]))
+	}
+	return level, msg, attrs, true
+}
+
+// Forwards the structured logs the plugin wrote to stderr to the logger, with the name of the plugin attached.
+// Returns the rest of stderr, which is reported as the error when the plugin fails
+func forwardLogs(ctx context.Context, logger *slog.Logger, name string, stderr []byte) string {
+	var rest strings.Builder
+	for _, line := range strings.SplitAfter(string(stderr), "\n") {
+		level, msg, attrs, ok := parseLog(line)
+		if !ok {
+			rest.WriteString(line)
+			continue
+		}
+		logger.Log(ctx, level, msg, append([]any{slog.String("plugin", name)}, attrs...)...)
+	}
+	return rest.String()
+}
+
+// Logs anything else the plugin wrote to stderr when it succeeded, which would otherwise be lost
+func logUnstructured(ctx context.Context, logger *slog.Logger, name string, stderr string) {
+	if strings.TrimSpace(stderr) != "" {
+		logger.DebugContext(ctx, "plugin wrote to stderr", "plugin", name, "stderr", stderr)
+	}
+}
@@ -0,0 +1,84 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/plugin"
+)
+
+// Returns a logger which records everything, and a function returning the records logged so far
+func captureLogs() (*slog.Logger, func(t *testing.T) []map[string]interface{}) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return logger, func(t *testing.T) []map[string]interface{} {
+		var records []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var record map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &record))
+			delete(record, "time")
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+func TestForwardLogs(t *testing.T) {
+	logger, records := captureLogs()
+	stderr := `{"time":"2024-01-01T00:00:00Z","level":"ERROR","msg":"broken","file":"VERSION"}
+panic: not json
+{"msg":"no level"}
+{"level":"WARN"}
+`
+	rest := forwardLogs(context.Background(), logger, "test", []byte(stderr))
+
+	assert.Equal(t, "panic: not json\n{\"level\":\"WARN\"}\n", rest)
+	assert.Equal(t, []map[string]interface{}{
+		{"level": "ERROR", "msg": "broken", "plugin": "test", "file": "VERSION"},
+		{"level": "INFO", "msg": "no level", "plugin": "test"},
+	}, records(t))
+}
+
+func TestPluginLogsAreForwarded(t *testing.T) {
+	logger, records := captureLogs()
+	settings, err := plugin.NewSettings(map[string]interface{}{"log": "hello"})
+	require.NoError(t, err)
+	runner := &Runner{
+		Plugin:   config.Plugin{Name: "test", URL: "file://" + buildPlugin(t), VersionedFile: "VERSION"},
+		Root:     newProject(t),
+		Registry: NewRegistry(),
+		Logger:   logger,
+	}
+	defer runner.Registry.Close(context.Background())
+
+	_, err = plugin.NewVersionGetterSetterServiceClient(runner).Request(context.Background(), &plugin.RequestMessage{
+		Request: &plugin.RequestMessage_GetVersion{GetVersion: &plugin.GetVersionRequest{FilePath: "VERSION", Settings: settings}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"level": "WARN", "msg": "hello", "plugin": "test", "attempt": float64(1)},
+		{"level": "DEBUG", "msg": "details", "plugin": "test"},
+		{"level": "DEBUG", "msg": "plugin wrote to stderr", "plugin": "test", "stderr": "not structured\n"},
+	}, records(t))
+}
+
+func TestExecPluginLogsAreLeftOutOfErrors(t *testing.T) {
+	logger, records := captureLogs()
+	runner := newExecRunner(t, newProject(t), config.Plugin{})
+	runner.Logger = logger
+
+	_, err := getVersionWith(t, runner, map[string]interface{}{"log": "hello", "read": "missing.txt"})
+	assert.EqualError(t, err, "not structured\nopen missing.txt: no such file or directory\n")
+	assert.Len(t, records(t), 2)
+}
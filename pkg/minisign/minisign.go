@@ -0,0 +1,143 @@
+// Verifies signatures made with minisign (https://jedisct1.github.io/minisign/)
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Signs the message itself. Made by minisign -l, and by versions before 0.11
+const ALGORITHM_LEGACY string = "Ed"
+
+// Signs the BLAKE2b-512 hash of the message. The default since minisign 0.11
+const ALGORITHM_PREHASHED string = "ED"
+
+const UNTRUSTED_COMMENT_PREFIX string = "untrusted comment: "
+const TRUSTED_COMMENT_PREFIX string = "trusted comment: "
+
+type KeyID [8]byte
+
+// Formats the key ID the way minisign prints it
+func (id KeyID) String() string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(id[:]))
+}
+
+type PublicKey struct {
+	ID  KeyID
+	Key ed25519.PublicKey
+}
+
+// Returns the base64 encoded line of the key file, dropping its comment
+func keyLine(contents string) string {
+	for _, line := range strings.Split(strings.TrimSpace(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, UNTRUSTED_COMMENT_PREFIX) {
+			return line
+		}
+	}
+	return ""
+}
+
+// Parses a public key, either the contents of a minisign.pub file or just its base64 encoded line
+func ParsePublicKey(contents string) (PublicKey, error) {
+	decoded, err := base64.StdEncoding.DecodeString(keyLine(contents))
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("invalid minisign public key: %w", err)
+	}
+	if len(decoded) != 2+8+ed25519.PublicKeySize || string(decoded[:2]) != ALGORITHM_LEGACY {
+		return PublicKey{}, errors.New("invalid minisign public key: expected an Ed25519 key")
+	}
+
+	key := PublicKey{Key: ed25519.PublicKey(decoded[10:])}
+	copy(key.ID[:], decoded[2:10])
+	return key, nil
+}
+
+type Signature struct {
+	// Either ALGORITHM_LEGACY or ALGORITHM_PREHASHED
+	Algorithm string
+	KeyID     KeyID
+	Signature []byte
+	// The comment covered by the global signature, e.g. the timestamp and file name
+	TrustedComment  string
+	GlobalSignature []byte
+}
+
+// Parses the contents of a .minisig file
+func ParseSignature(contents []byte) (Signature, error) {
+	lines := strings.Split(strings.TrimRight(string(contents), "\r\n"), "\n")
+	if len(lines) != 4 {
+		return Signature{}, errors.New("invalid minisign signature: expected 4 lines")
+	}
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	if !strings.HasPrefix(lines[0], UNTRUSTED_COMMENT_PREFIX) || !strings.HasPrefix(lines[2], TRUSTED_COMMENT_PREFIX) {
+		return Signature{}, errors.New("invalid minisign signature: missing comment lines")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(decoded) != 2+8+ed25519.SignatureSize {
+		return Signature{}, errors.New("invalid minisign signature: malformed signature line")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return Signature{}, errors.New("invalid minisign signature: malformed global signature line")
+	}
+
+	signature := Signature{
+		Algorithm:       string(decoded[:2]),
+		Signature:       decoded[10:],
+		TrustedComment:  strings.TrimPrefix(lines[2], TRUSTED_COMMENT_PREFIX),
+		GlobalSignature: global,
+	}
+	copy(signature.KeyID[:], decoded[2:10])
+	if signature.Algorithm != ALGORITHM_LEGACY && signature.Algorithm != ALGORITHM_PREHASHED {
+		return Signature{}, fmt.Errorf("invalid minisign signature: unsupported algorithm %q", signature.Algorithm)
+	}
+	return signature, nil
+}
+
+// Checks the signature of the message was made by the key, including the trusted comment
+func (k PublicKey) Verify(message []byte, signature Signature) error {
+	if signature.KeyID != k.ID {
+		return fmt.Errorf("signed by key %s rather than %s", signature.KeyID, k.ID)
+	}
+
+	signed := message
+	if signature.Algorithm == ALGORITHM_PREHASHED {
+		hash := blake2b.Sum512(message)
+		signed = hash[:]
+	}
+	if !ed25519.Verify(k.Key, signed, signature.Signature) {
+		return errors.New("signature verification failed")
+	}
+
+	global := bytes.Join([][]byte{signature.Signature, []byte(signature.TrustedComment)}, nil)
+	if !ed25519.Verify(k.Key, global, signature.GlobalSignature) {
+		return errors.New("trusted comment verification failed")
+	}
+	return nil
+}
+
+// Checks the message was signed by one of the keys
+func Verify(keys []PublicKey, message []byte, signature Signature) error {
+	for _, key := range keys {
+		if key.ID == signature.KeyID {
+			return key.Verify(message, signature)
+		}
+	}
+	return fmt.Errorf("signed by key %s, which isn't trusted", signature.KeyID)
+}
+
+// Encodes the public key as the base64 line of a minisign.pub file
+func (k PublicKey) String() string {
+	return base64.StdEncoding.EncodeToString(bytes.Join([][]byte{[]byte(ALGORITHM_LEGACY), k.ID[:], k.Key}, nil))
+}
@@ -0,0 +1,79 @@
+package minisign
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alex-way/changesets/pkg/internal/minisigntest"
+)
+
+func newKey(t *testing.T, id byte) (PublicKey, ed25519.PrivateKey) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return PublicKey{ID: KeyID{id, 2, 3, 4, 5, 6, 7, 8}, Key: public}, private
+}
+
+func TestParsePublicKey(t *testing.T) {
+	// The public key of minisign's author
+	key, err := ParsePublicKey("untrusted comment: minisign public key 3B4A9C22E2C9AE91\nRWSRrsniIpxKO3UIoPaZbF0kVGlflrQyrtjvJWBEeVBLjoDd9asIyvLF\n")
+	require.NoError(t, err)
+	assert.Equal(t, "3B4A9C22E2C9AE91", key.ID.String())
+
+	roundtrip, err := ParsePublicKey(key.String())
+	require.NoError(t, err)
+	assert.Equal(t, key, roundtrip)
+
+	_, err = ParsePublicKey("not a key")
+	assert.ErrorContains(t, err, "invalid minisign public key")
+}
+
+func TestVerify(t *testing.T) {
+	key, private := newKey(t, 1)
+	other, _ := newKey(t, 9)
+	message := []byte("plugin contents")
+
+	signature, err := ParseSignature(minisigntest.Sign(private, key.ID, message, "timestamp:1700000000\tfile:plugin.wasm"))
+	require.NoError(t, err)
+	assert.Equal(t, ALGORITHM_PREHASHED, signature.Algorithm)
+	assert.Equal(t, "timestamp:1700000000\tfile:plugin.wasm", signature.TrustedComment)
+
+	assert.NoError(t, Verify([]PublicKey{other, key}, message, signature))
+	assert.EqualError(t, Verify([]PublicKey{key}, []byte("tampered"), signature), "signature verification failed")
+	assert.EqualError(t, Verify([]PublicKey{other}, message, signature), "signed by key 0807060504030201, which isn't trusted")
+
+	signature.TrustedComment = "forged"
+	assert.EqualError(t, key.Verify(message, signature), "trusted comment verification failed")
+}
+
+func TestVerifyLegacySignature(t *testing.T) {
+	key, private := newKey(t, 1)
+	message := []byte("plugin contents")
+
+	// Legacy signatures sign the message rather than its hash
+	signature := ed25519.Sign(private, message)
+	global := ed25519.Sign(private, append(append([]byte{}, signature...), "comment"...))
+	line := append(append([]byte(ALGORITHM_LEGACY), key.ID[:]...), signature...)
+	contents := strings.Join([]string{
+		"untrusted comment: legacy",
+		base64.StdEncoding.EncodeToString(line),
+		"trusted comment: comment",
+		base64.StdEncoding.EncodeToString(global),
+	}, "\n")
+
+	parsed, err := ParseSignature([]byte(contents))
+	require.NoError(t, err)
+	assert.NoError(t, key.Verify(message, parsed))
+}
+
+func TestParseSignatureErrors(t *testing.T) {
+	_, err := ParseSignature([]byte("just one line"))
+	assert.EqualError(t, err, "invalid minisign signature: expected 4 lines")
+
+	_, err = ParseSignature([]byte("untrusted comment: a\n!!!\ntrusted comment: b\n!!!\n"))
+	assert.EqualError(t, err, "invalid minisign signature: malformed signature line")
+}
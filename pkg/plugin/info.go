@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The newest protocol version spoken by the host. Raised whenever a request is added which older plugins can't handle
+const PROTOCOL_VERSION uint32 = 2
+
+// The oldest protocol version the host still speaks. Plugins from before the GetInfo request speak protocol 1
+const MIN_PROTOCOL_VERSION uint32 = 1
+
+// Returns the info assumed for plugins which don't handle the GetInfo request. Plugins published before it only
+// handle GetVersion and SetVersion, so they're never sent a WriteChangelog request they'd misread
+func LegacyInfo() *GetInfoResponse {
+	return &GetInfoResponse{
+		ProtocolVersion: 1,
+		Capabilities:    []Capability{Capability_CAPABILITY_GET_VERSION, Capability_CAPABILITY_SET_VERSION},
+	}
+}
+
+// Returns the capability a plugin needs to handle the request
+func CapabilityOf(req *RequestMessage) Capability {
+	switch req.Request.(type) {
+	case *RequestMessage_GetVersion:
+		return Capability_CAPABILITY_GET_VERSION
+	case *RequestMessage_SetVersion:
+		return Capability_CAPABILITY_SET_VERSION
+	case *RequestMessage_WriteChangelog:
+		return Capability_CAPABILITY_WRITE_CHANGELOG
+	}
+	return Capability_CAPABILITY_UNSPECIFIED
+}
+
+// Returns the capability in the form used by error messages, e.g. write_changelog
+func DescribeCapability(capability Capability) string {
+	return strings.ToLower(strings.TrimPrefix(capability.String(), "CAPABILITY_"))
+}
+
+// Whether the plugin can handle requests needing the capability. Every plugin can handle unspecified ones, such as GetInfo
+func (x *GetInfoResponse) Supports(capability Capability) bool {
+	return capability == Capability_CAPABILITY_UNSPECIFIED || slices.Contains(x.GetCapabilities(), capability)
+}
+
+// Returns an error when the plugin speaks a protocol version the host doesn't
+func (x *GetInfoResponse) CheckProtocol(name string) error {
+	version := x.GetProtocolVersion()
+	if version > PROTOCOL_VERSION {
+		return fmt.Errorf("plugin %s speaks protocol version %d, but this version of changeset only supports up to %d. Upgrade changeset or pin an older version of the plugin", name, version, PROTOCOL_VERSION)
+	}
+	if version < MIN_PROTOCOL_VERSION {
+		return fmt.Errorf("plugin %s speaks protocol version %d, but this version of changeset requires at least %d. Upgrade the plugin", name, version, MIN_PROTOCOL_VERSION)
+	}
+	return nil
+}
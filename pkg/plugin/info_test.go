@@ -0,0 +1,29 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckProtocol(t *testing.T) {
+	assert.NoError(t, LegacyInfo().CheckProtocol("legacy"))
+	assert.NoError(t, (&GetInfoResponse{ProtocolVersion: PROTOCOL_VERSION}).CheckProtocol("current"))
+
+	err := (&GetInfoResponse{ProtocolVersion: PROTOCOL_VERSION + 1}).CheckProtocol("newer")
+	assert.ErrorContains(t, err, "plugin newer speaks protocol version 3, but this version of changeset only supports up to 2")
+
+	err = (&GetInfoResponse{}).CheckProtocol("unset")
+	assert.ErrorContains(t, err, "plugin unset speaks protocol version 0, but this version of changeset requires at least 1")
+}
+
+func TestSupports(t *testing.T) {
+	info := &GetInfoResponse{Capabilities: []Capability{Capability_CAPABILITY_GET_VERSION}}
+	getVersion := &RequestMessage{Request: &RequestMessage_GetVersion{GetVersion: &GetVersionRequest{}}}
+	writeChangelog := &RequestMessage{Request: &RequestMessage_WriteChangelog{WriteChangelog: &WriteChangelogRequest{}}}
+
+	assert.True(t, info.Supports(CapabilityOf(getVersion)))
+	assert.False(t, info.Supports(CapabilityOf(writeChangelog)))
+	assert.True(t, info.Supports(Capability_CAPABILITY_UNSPECIFIED))
+	assert.Equal(t, "write_changelog", DescribeCapability(CapabilityOf(writeChangelog)))
+}
@@ -0,0 +1,353 @@
+// Checks that a plugin conforms to the protocol, by running a standard set of requests against a fixture of its
+// versioned file. Plugin authors can call Run from a Go test, or use `changeset plugin test` from the command line
+package plugintest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	wasm "github.com/alex-way/changesets/pkg"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/plugin"
+	"github.com/alex-way/changesets/pkg/version"
+)
+
+type Options struct {
+	// The path of the plugin, e.g. plugin.wasm, or any URL a plugin can be configured with
+	URL string
+	// The name of the versioned file the fixture is written to, e.g. package.json
+	VersionedFile string
+	// The contents of a versioned file. The version should only appear once in it, as setting the version is
+	// expected to change nothing else
+	Fixture string
+	// The version the plugin should read from the fixture. Any valid version is accepted when empty
+	Version  string
+	Settings map[string]interface{}
+}
+
+// The plugin under test, which is loaded once and shared by the cases
+type Suite struct {
+	options  Options
+	plugin   config.Plugin
+	registry *wasm.Registry
+}
+
+// A single check of the suite, run in its own project directory
+type Case struct {
+	Name  string
+	check func(ctx context.Context, e *env) error
+}
+
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// Returns the URL of the plugin, with local paths made absolute as each case runs in its own directory
+func pluginURL(path string) (string, error) {
+	scheme := "file://"
+	if i := strings.Index(path, "://"); i >= 0 {
+		scheme, path = path[:i+3], path[i+3:]
+	}
+	if scheme != "file://" && scheme != "exec://" {
+		return scheme + path, nil
+	}
+	if scheme == "exec://" && !strings.ContainsRune(path, '/') {
+		// Looked up on the PATH
+		return scheme + path, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return scheme + abs, nil
+}
+
+// Resolves the plugin and pins its checksum, so that every case runs the same plugin
+func New(ctx context.Context, options Options) (*Suite, error) {
+	if options.VersionedFile == "" {
+		return nil, errors.New("plugintest: VersionedFile is required")
+	}
+	if options.Fixture == "" {
+		return nil, errors.New("plugintest: Fixture is required")
+	}
+	url, err := pluginURL(options.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Suite{
+		options:  options,
+		plugin:   config.Plugin{Name: filepath.Base(options.URL), URL: url, VersionedFile: options.VersionedFile, Settings: options.Settings},
+		registry: wasm.NewRegistry(),
+	}
+	s.plugin.SHA256, err = s.client(".").Checksum(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plugin %s: %w", options.URL, err)
+	}
+	return s, nil
+}
+
+// Releases the compiled plugin
+func (s *Suite) Close(ctx context.Context) error {
+	return s.registry.Close(ctx)
+}
+
+func (s *Suite) client(dir string) wasm.Client {
+	if s.plugin.IsExec() {
+		return &wasm.ExecRunner{Plugin: s.plugin, Root: dir, Registry: s.registry}
+	}
+	return &wasm.Runner{Plugin: s.plugin, Root: dir, Registry: s.registry}
+}
+
+// Runs the case in the directory, which should be empty
+func (s *Suite) RunCase(ctx context.Context, c Case, dir string) error {
+	settings, err := plugin.NewSettings(s.options.Settings)
+	if err != nil {
+		return err
+	}
+	client := s.client(dir)
+	return c.check(ctx, &env{
+		options:  s.options,
+		dir:      dir,
+		client:   client,
+		service:  plugin.NewVersionGetterSetterServiceClient(client),
+		settings: settings,
+	})
+}
+
+// Runs every case, each in a temporary directory
+func (s *Suite) RunAll(ctx context.Context) []Result {
+	var results []Result
+	for _, c := range Cases() {
+		start := time.Now()
+		err := func() error {
+			dir, err := os.MkdirTemp("", "changeset-plugintest")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			return s.RunCase(ctx, c, dir)
+		}()
+		results = append(results, Result{Name: c.Name, Err: err, Duration: time.Since(start)})
+	}
+	return results
+}
+
+// Runs the suite against the plugin as subtests of t
+func Run(t *testing.T, options Options) {
+	t.Helper()
+	ctx := context.Background()
+	s, err := New(ctx, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close(ctx) })
+
+	for _, c := range Cases() {
+		t.Run(c.Name, func(t *testing.T) {
+			if err := s.RunCase(ctx, c, t.TempDir()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// Runs the suite against the plugin, writing the results in the format of `go test -v`. Returns whether every case
+// passed
+func Report(ctx context.Context, w io.Writer, options Options) (bool, error) {
+	s, err := New(ctx, options)
+	if err != nil {
+		return false, err
+	}
+	defer s.Close(ctx)
+
+	passed := true
+	for _, result := range s.RunAll(ctx) {
+		fmt.Fprintf(w, "=== RUN   %s\n", result.Name)
+		if result.Err != nil {
+			passed = false
+			fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(strings.TrimRight(result.Err.Error(), "\n"), "\n", "\n    "))
+			fmt.Fprintf(w, "--- FAIL: %s (%.2fs)\n", result.Name, result.Duration.Seconds())
+		} else {
+			fmt.Fprintf(w, "--- PASS: %s (%.2fs)\n", result.Name, result.Duration.Seconds())
+		}
+	}
+	if passed {
+		fmt.Fprintln(w, "PASS")
+	} else {
+		fmt.Fprintln(w, "FAIL")
+	}
+	return passed, nil
+}
+
+// The project directory and plugin a case runs against
+type env struct {
+	options  Options
+	dir      string
+	client   wasm.Client
+	service  plugin.VersionGetterSetterServiceClient
+	settings *structpb.Struct
+}
+
+func (e *env) path() string {
+	return filepath.Join(e.dir, e.options.VersionedFile)
+}
+
+func (e *env) writeFixture(contents string) error {
+	if err := os.MkdirAll(filepath.Dir(e.path()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(e.path(), []byte(contents), 0644)
+}
+
+func (e *env) getVersion(ctx context.Context) (*plugin.Response, error) {
+	return e.service.Request(ctx, &plugin.RequestMessage{Request: &plugin.RequestMessage_GetVersion{
+		GetVersion: &plugin.GetVersionRequest{FilePath: e.options.VersionedFile, Settings: e.settings},
+	}})
+}
+
+func (e *env) setVersion(ctx context.Context, version string) (*plugin.Response, error) {
+	return e.service.Request(ctx, &plugin.RequestMessage{Request: &plugin.RequestMessage_SetVersion{
+		SetVersion: &plugin.SetVersionRequest{FilePath: e.options.VersionedFile, Version: version, Settings: e.settings},
+	}})
+}
+
+// Checks the request succeeded with an OK status
+func succeeded(request string, resp *plugin.Response, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", request, err)
+	}
+	if resp.GetStatus().GetCode() != 0 {
+		return fmt.Errorf("%s returned status %d: %s", request, resp.GetStatus().GetCode(), resp.GetStatus().GetMessage())
+	}
+	return nil
+}
+
+// Checks the request failed by returning an error status, rather than by crashing or succeeding
+func failedWithStatus(request string, resp *plugin.Response, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s should return an error status, but the plugin failed: %w", request, err)
+	}
+	if resp.GetStatus() == nil {
+		return fmt.Errorf("%s should return an error status, but returned no status", request)
+	}
+	if resp.GetStatus().GetCode() == 0 {
+		return fmt.Errorf("%s should return an error status, but succeeded", request)
+	}
+	if resp.GetStatus().GetMessage() == "" {
+		return fmt.Errorf("%s returned status %d without a message explaining it", request, resp.GetStatus().GetCode())
+	}
+	return nil
+}
+
+// Reads the version of the fixture, checking it's the expected one
+func (e *env) readFixture(ctx context.Context) (string, error) {
+	if err := e.writeFixture(e.options.Fixture); err != nil {
+		return "", err
+	}
+	resp, err := e.getVersion(ctx)
+	if err := succeeded("GetVersion", resp, err); err != nil {
+		return "", err
+	}
+
+	read := resp.GetGetVersion().GetVersion()
+	if e.options.Version != "" && read != e.options.Version {
+		return "", fmt.Errorf("GetVersion returned %q, expected %q", read, e.options.Version)
+	}
+	if _, err := version.ParseVersion(read); err != nil {
+		return "", fmt.Errorf("GetVersion returned %q, which isn't a valid version: %w", read, err)
+	}
+	return read, nil
+}
+
+// Sets the fixture to the next minor version, returning the previous and next versions
+func (e *env) bumpFixture(ctx context.Context) (string, string, error) {
+	current, err := e.readFixture(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	next, _ := version.ParseVersion(current)
+	next.BumpMinor()
+
+	resp, err := e.setVersion(ctx, next.String())
+	if err := succeeded("SetVersion", resp, err); err != nil {
+		return "", "", err
+	}
+	return current, next.String(), nil
+}
+
+// Returns the cases of the suite, in the order they're run
+func Cases() []Case {
+	return []Case{
+		{Name: "Handshake", check: func(ctx context.Context, e *env) error {
+			info, err := e.client.Info(ctx)
+			if err != nil {
+				return err
+			}
+			for _, capability := range []plugin.Capability{plugin.Capability_CAPABILITY_GET_VERSION, plugin.Capability_CAPABILITY_SET_VERSION} {
+				if !info.Supports(capability) {
+					return fmt.Errorf("the plugin doesn't list %s in its capabilities", plugin.DescribeCapability(capability))
+				}
+			}
+			return nil
+		}},
+		{Name: "GetVersion", check: func(ctx context.Context, e *env) error {
+			_, err := e.readFixture(ctx)
+			return err
+		}},
+		{Name: "GetVersionOfMissingFile", check: func(ctx context.Context, e *env) error {
+			resp, err := e.getVersion(ctx)
+			return failedWithStatus("GetVersion of a missing file", resp, err)
+		}},
+		{Name: "GetVersionOfEmptyFile", check: func(ctx context.Context, e *env) error {
+			if err := e.writeFixture(""); err != nil {
+				return err
+			}
+			resp, err := e.getVersion(ctx)
+			return failedWithStatus("GetVersion of an empty file", resp, err)
+		}},
+		{Name: "SetVersion", check: func(ctx context.Context, e *env) error {
+			_, next, err := e.bumpFixture(ctx)
+			if err != nil {
+				return err
+			}
+			resp, err := e.getVersion(ctx)
+			if err := succeeded("GetVersion after SetVersion", resp, err); err != nil {
+				return err
+			}
+			if read := resp.GetGetVersion().GetVersion(); read != next {
+				return fmt.Errorf("GetVersion after SetVersion returned %q, expected %q", read, next)
+			}
+			return nil
+		}},
+		{Name: "SetVersionPreservesFormatting", check: func(ctx context.Context, e *env) error {
+			current, next, err := e.bumpFixture(ctx)
+			if err != nil {
+				return err
+			}
+			contents, err := os.ReadFile(e.path())
+			if err != nil {
+				return err
+			}
+			if !strings.Contains(e.options.Fixture, current) {
+				return fmt.Errorf("the fixture doesn't contain the version %q, so its formatting can't be checked", current)
+			}
+			expected := strings.Replace(e.options.Fixture, current, next, 1)
+			if string(contents) != expected {
+				return fmt.Errorf("SetVersion should only change the version, expected:\n%s\ngot:\n%s", expected, contents)
+			}
+			return nil
+		}},
+	}
+}
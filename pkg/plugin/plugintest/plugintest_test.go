@@ -0,0 +1,85 @@
+package plugintest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildPlugin(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.wasm")
+	cmd := exec.Command(filepath.Join(runtime.GOROOT(), "bin", "go"), "build", "-o", path, "../../testdata/plugin")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("failed to build the test plugin: %s", output)
+	}
+	return path
+}
+
+func findCase(t *testing.T, name string) Case {
+	for _, c := range Cases() {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no case named %s", name)
+	return Case{}
+}
+
+func TestConformingPlugin(t *testing.T) {
+	Run(t, Options{URL: buildPlugin(t), VersionedFile: "VERSION", Fixture: "1.2.3\n", Version: "1.2.3"})
+}
+
+func TestReportFailures(t *testing.T) {
+	ctx := context.Background()
+	path := buildPlugin(t)
+
+	var output bytes.Buffer
+	passed, err := Report(ctx, &output, Options{URL: path, VersionedFile: "VERSION", Fixture: "1.2.3\n"})
+	require.NoError(t, err)
+	assert.True(t, passed)
+	assert.Contains(t, output.String(), "--- PASS: SetVersionPreservesFormatting")
+	assert.Contains(t, output.String(), "\nPASS\n")
+
+	output.Reset()
+	passed, err = Report(ctx, &output, Options{
+		URL:           path,
+		VersionedFile: "VERSION",
+		Fixture:       "1.2.3\n",
+		Settings:      map[string]interface{}{"read": "missing.txt"},
+	})
+	require.NoError(t, err)
+	assert.False(t, passed)
+	assert.Contains(t, output.String(), "--- PASS: Handshake")
+	assert.Contains(t, output.String(), "GetVersion of a missing file should return an error status, but the plugin failed")
+	assert.Contains(t, output.String(), "--- FAIL: GetVersionOfMissingFile")
+	assert.Contains(t, output.String(), "\nFAIL\n")
+}
+
+func TestCasesCheckTheFixture(t *testing.T) {
+	ctx := context.Background()
+	suite, err := New(ctx, Options{URL: buildPlugin(t), VersionedFile: "VERSION", Fixture: "1.2.3\n\n", Version: "1.2.3"})
+	require.NoError(t, err)
+	defer suite.Close(ctx)
+
+	assert.NoError(t, suite.RunCase(ctx, findCase(t, "SetVersion"), t.TempDir()))
+	err = suite.RunCase(ctx, findCase(t, "SetVersionPreservesFormatting"), t.TempDir())
+	assert.ErrorContains(t, err, "SetVersion should only change the version")
+
+	suite.options.Version = "2.0.0"
+	err = suite.RunCase(ctx, findCase(t, "GetVersion"), t.TempDir())
+	assert.EqualError(t, err, `GetVersion returned "1.2.3", expected "2.0.0"`)
+}
+
+func TestNewRequiresAFixture(t *testing.T) {
+	_, err := New(context.Background(), Options{URL: "plugin.wasm", VersionedFile: "VERSION"})
+	assert.EqualError(t, err, "plugintest: Fixture is required")
+}
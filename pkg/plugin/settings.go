@@ -0,0 +1,19 @@
+package plugin
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Converts the free-form plugin settings from the config into the Struct sent with each request, returning nil when there are none
+func NewSettings(settings map[string]interface{}) (*structpb.Struct, error) {
+	if len(settings) == 0 {
+		return nil, nil
+	}
+	value, err := structpb.NewStruct(settings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin settings: %w", err)
+	}
+	return value, nil
+}
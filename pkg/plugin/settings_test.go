@@ -0,0 +1,25 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSettings(t *testing.T) {
+	settings, err := NewSettings(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, settings)
+
+	settings, err = NewSettings(map[string]interface{}{
+		"table":   "tool.poetry",
+		"pattern": `version = "(.*)"`,
+		"nested":  map[string]interface{}{"enabled": true, "depth": float64(2)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "tool.poetry", settings.Fields["table"].GetStringValue())
+	assert.Equal(t, true, settings.Fields["nested"].GetStructValue().Fields["enabled"].GetBoolValue())
+
+	_, err = NewSettings(map[string]interface{}{"invalid": struct{}{}})
+	assert.ErrorContains(t, err, "invalid plugin settings")
+}
@@ -0,0 +1,140 @@
+package project
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/alex-way/changesets/pkg/changeset"
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/lock"
+)
+
+// The resolved location of a changesets project
+type Project struct {
+	// The absolute path of the directory containing the .changeset directory
+	Root string
+	// An explicit config file to use, discovered from Root when empty
+	ConfigPath string
+	// Config overrides from the command line in the form key=value
+	Overrides []string
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Walks up from the start directory to find the project root, which is the first directory
+// containing a .changeset directory or config. The search stops at the root of the git repository,
+// which is used as the project root when nothing is found. Otherwise the start directory is returned.
+func FindRoot(start string) (string, error) {
+	start, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+
+	dir := start
+	for {
+		if exists(filepath.Join(dir, config.CHANGESET_DIRECTORY)) {
+			return dir, nil
+		}
+		found, err := config.HasConfig(dir)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			return dir, nil
+		}
+		if exists(filepath.Join(dir, ".git")) {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return start, nil
+		}
+		dir = parent
+	}
+}
+
+// Resolves the project from the working directory, which defaults to the current directory.
+// A relative config path is resolved against the working directory.
+func Resolve(cwd string, config_path string, overrides []string) (Project, error) {
+	if cwd == "" {
+		var err error
+		cwd, err = os.Getwd()
+		if err != nil {
+			return Project{}, err
+		}
+	}
+
+	root, err := FindRoot(cwd)
+	if err != nil {
+		return Project{}, err
+	}
+
+	if config_path != "" && !filepath.IsAbs(config_path) {
+		config_path, err = filepath.Abs(filepath.Join(cwd, config_path))
+		if err != nil {
+			return Project{}, err
+		}
+	}
+
+	return Project{Root: root, ConfigPath: config_path, Overrides: overrides}, nil
+}
+
+// Returns the path relative to the project root
+func (p Project) Path(elem ...string) string {
+	return filepath.Join(append([]string{p.Root}, elem...)...)
+}
+
+// Returns the path of the plugin lockfile
+func (p Project) LockPath() string {
+	return p.Path(config.CHANGESET_DIRECTORY, lock.LOCKFILE_NAME)
+}
+
+func (p Project) configOptions() config.Options {
+	return config.Options{Root: p.Root, Path: p.ConfigPath, Overrides: p.Overrides}
+}
+
+// Reads the config of the project, applying all layers
+func (p Project) GetConfig() (config.Config, error) {
+	return config.GetConfig(p.configOptions())
+}
+
+// Reads the config of the project, along with where each field was set
+func (p Project) ResolveConfig() (config.Resolved, error) {
+	return config.Resolve(p.configOptions())
+}
+
+// Returns how changeset files are read according to the config, or the defaults when there is no config
+func (p Project) ChangesetOptions() (changeset.Options, error) {
+	_config, err := p.GetConfig()
+	if errors.Is(err, config.ErrNotFound) {
+		return changeset.Options{}, nil
+	}
+	if err != nil {
+		return changeset.Options{}, err
+	}
+
+	vocabulary, err := _config.Vocabulary()
+	if err != nil {
+		return changeset.Options{}, err
+	}
+	opts := changeset.Options{Vocabulary: vocabulary, Ignore: _config.Ignore}
+	if _config.Packages != nil {
+		opts.IgnoredPackages = _config.Packages.Ignore
+		opts.PrivatePackages = _config.Packages.Private
+	}
+	return opts, nil
+}
+
+// Reads the pending changes of the project
+func (p Project) GetChanges() ([]changeset.Change, error) {
+	opts, err := p.ChangesetOptions()
+	if err != nil {
+		return nil, err
+	}
+	return changeset.GetChanges(p.Root, opts)
+}
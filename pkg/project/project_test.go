@@ -0,0 +1,43 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindRootWalksUpToChangesetDirectory(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "packages", "app")
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, ".changeset"), 0755))
+	assert.NoError(t, os.MkdirAll(nested, 0755))
+
+	found, err := FindRoot(nested)
+	assert.NoError(t, err)
+	assert.Equal(t, root, found)
+}
+
+func TestFindRootStopsAtGitRoot(t *testing.T) {
+	outer := t.TempDir()
+	repo := filepath.Join(outer, "repo")
+	nested := filepath.Join(repo, "src")
+	assert.NoError(t, os.MkdirAll(filepath.Join(outer, ".changeset"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0755))
+	assert.NoError(t, os.MkdirAll(nested, 0755))
+
+	found, err := FindRoot(nested)
+	assert.NoError(t, err)
+	assert.Equal(t, repo, found)
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, ".changeset"), 0755))
+
+	project, err := Resolve(root, "ci/changeset.json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, root, project.Root)
+	assert.Equal(t, filepath.Join(root, "ci", "changeset.json"), project.ConfigPath)
+}
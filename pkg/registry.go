@@ -0,0 +1,145 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"golang.org/x/sync/singleflight"
+)
+
+// Keeps the compiled plugins of the process, so that each plugin is only fetched, checked and compiled once no
+// matter how many requests are sent to it
+type Registry struct {
+	flight singleflight.Group
+
+	mu        sync.Mutex
+	compiled  map[string]*runtimeAndCode
+	checksums map[string]string
+	cache     wazero.CompilationCache
+	closed    bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{compiled: map[string]*runtimeAndCode{}, checksums: map[string]string{}}
+}
+
+// The registry used by runners which don't set their own
+var DefaultRegistry = NewRegistry()
+
+// Closes the runtimes of the default registry. Called once the CLI exits
+func Close(ctx context.Context) error {
+	return DefaultRegistry.Close(ctx)
+}
+
+// The memory limit is set on the runtime, which refuses to grow the memory of a plugin past it, so plugins with
+// different limits can't share a runtime
+func registryKey(sha string, _limits limits) string {
+	return fmt.Sprintf("%s:%d", sha, _limits.memoryPages)
+}
+
+// Returns the compiled plugin for the key, compiling it with the given function the first time it's asked for.
+// Concurrent callers of the same key wait for a single compilation
+func (r *Registry) load(key string, compile func() (*runtimeAndCode, error)) (*runtimeAndCode, error) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, errors.New("plugin registry is closed")
+	}
+	if compiled, ok := r.compiled[key]; ok {
+		r.mu.Unlock()
+		return compiled, nil
+	}
+	r.mu.Unlock()
+
+	value, err, _ := r.flight.Do(key, func() (interface{}, error) {
+		r.mu.Lock()
+		if compiled, ok := r.compiled[key]; ok {
+			r.mu.Unlock()
+			return compiled, nil
+		}
+		r.mu.Unlock()
+
+		compiled, err := compile()
+		if err != nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.closed {
+			if compiled.rt != nil {
+				compiled.rt.Close(context.Background())
+			}
+			return nil, errors.New("plugin registry is closed")
+		}
+		r.compiled[key] = compiled
+		return compiled, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	compiled, ok := value.(*runtimeAndCode)
+	if !ok {
+		return nil, fmt.Errorf("returned value was not a compiled module")
+	}
+	return compiled, nil
+}
+
+// Returns the compilation cache shared by every runtime of the registry, creating it with the given function
+func (r *Registry) compilationCache(create func() (wazero.CompilationCache, error)) (wazero.CompilationCache, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cache != nil {
+		return r.cache, nil
+	}
+	cache, err := create()
+	if err != nil {
+		return nil, err
+	}
+	r.cache = cache
+	return cache, nil
+}
+
+// Returns the checksum previously calculated for the plugin URL
+func (r *Registry) checksum(uri string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sum, ok := r.checksums[uri]
+	return sum, ok
+}
+
+func (r *Registry) setChecksum(uri string, sum string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checksums[uri] = sum
+}
+
+// Closes every runtime and the compilation cache. Plugins can't be loaded from the registry once it's closed
+func (r *Registry) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for key, compiled := range r.compiled {
+		// Executable plugins only keep their handshake, so have no runtime
+		if compiled.rt == nil {
+			delete(r.compiled, key)
+			continue
+		}
+		if err := compiled.rt.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		delete(r.compiled, key)
+	}
+	if r.cache != nil {
+		if err := r.cache.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		r.cache = nil
+	}
+	r.closed = true
+	return errors.Join(errs...)
+}
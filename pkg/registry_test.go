@@ -0,0 +1,82 @@
+package wasm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/plugin"
+)
+
+func newRunner(t testing.TB, registry *Registry, root string) *Runner {
+	return &Runner{
+		Plugin:   config.Plugin{Name: "test", URL: "file://" + buildPlugin(t), VersionedFile: "VERSION"},
+		Root:     root,
+		Registry: registry,
+	}
+}
+
+func getVersionRequest() *plugin.RequestMessage {
+	return &plugin.RequestMessage{Request: &plugin.RequestMessage_GetVersion{
+		GetVersion: &plugin.GetVersionRequest{FilePath: "VERSION"},
+	}}
+}
+
+func TestRegistryReusesCompiledPlugins(t *testing.T) {
+	registry := NewRegistry()
+	root := newProject(t)
+
+	for i := 0; i < 3; i++ {
+		client := plugin.NewVersionGetterSetterServiceClient(newRunner(t, registry, root))
+		resp, err := client.Request(context.Background(), getVersionRequest())
+		require.NoError(t, err)
+		assert.Equal(t, "1.2.3", resp.GetGetVersion().Version)
+	}
+	assert.Len(t, registry.compiled, 1)
+	assert.Len(t, registry.checksums, 1)
+
+	// A different memory limit needs its own runtime
+	runner := newRunner(t, registry, root)
+	runner.Plugin.Limits = &config.Limits{MemoryPages: 1024}
+	_, err := plugin.NewVersionGetterSetterServiceClient(runner).Request(context.Background(), getVersionRequest())
+	require.NoError(t, err)
+	assert.Len(t, registry.compiled, 2)
+
+	require.NoError(t, registry.Close(context.Background()))
+	assert.Empty(t, registry.compiled)
+	_, err = plugin.NewVersionGetterSetterServiceClient(newRunner(t, registry, root)).Request(context.Background(), getVersionRequest())
+	assert.EqualError(t, err, "loadBytes: plugin registry is closed")
+}
+
+// Every request compiles the plugin from scratch, apart from what's in the on-disk compilation cache
+func BenchmarkGetVersionCold(b *testing.B) {
+	root := newProject(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		registry := NewRegistry()
+		client := plugin.NewVersionGetterSetterServiceClient(newRunner(b, registry, root))
+		if _, err := client.Request(context.Background(), getVersionRequest()); err != nil {
+			b.Fatal(err)
+		}
+		registry.Close(context.Background())
+	}
+}
+
+// Every request reuses the plugin compiled by the first
+func BenchmarkGetVersionWarm(b *testing.B) {
+	root := newProject(b)
+	registry := NewRegistry()
+	defer registry.Close(context.Background())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client := plugin.NewVersionGetterSetterServiceClient(newRunner(b, registry, root))
+		if _, err := client.Request(context.Background(), getVersionRequest()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
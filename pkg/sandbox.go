@@ -0,0 +1,221 @@
+package wasm
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/experimental/sysfs"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Returned when a plugin accesses a file other than those it was given for the request
+type AccessError struct {
+	Plugin string
+	// The path relative to the project root
+	Path string
+	// Either read or write
+	Op string
+}
+
+func (e *AccessError) Error() string {
+	return fmt.Sprintf("plugin %s was denied %s access to %s", e.Plugin, e.Op, e.Path)
+}
+
+// A filesystem exposing only the given files of the project root to a plugin. Readable files can't be written to,
+// and directories only list the files which are visible. Every denied access is recorded
+type sandboxFS struct {
+	fs       experimentalsys.FS
+	plugin   string
+	readable map[string]bool
+	writable map[string]bool
+
+	mu         sync.Mutex
+	violations []error
+}
+
+// Normalises the path to be relative to the project root, using forward slashes
+func sandboxPath(name string) string {
+	name = path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "/"))
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func newSandboxFS(root string, plugin string, readable []string, writable []string) *sandboxFS {
+	sandbox := &sandboxFS{
+		fs:       sysfs.DirFS(root),
+		plugin:   plugin,
+		readable: map[string]bool{},
+		writable: map[string]bool{},
+	}
+	for _, name := range readable {
+		sandbox.readable[sandboxPath(name)] = true
+	}
+	for _, name := range writable {
+		sandbox.writable[sandboxPath(name)] = true
+	}
+	return sandbox
+}
+
+// Whether the path is a directory containing one of the visible files
+func (s *sandboxFS) isParent(name string) bool {
+	if name == "." {
+		return true
+	}
+	for _, files := range []map[string]bool{s.readable, s.writable} {
+		for file := range files {
+			if strings.HasPrefix(file, name+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *sandboxFS) canRead(name string) bool {
+	return s.readable[name] || s.writable[name] || s.isParent(name)
+}
+
+// Records the violation and returns the error reported to the plugin
+func (s *sandboxFS) deny(op string, name string) experimentalsys.Errno {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, err := range s.violations {
+		if access := err.(*AccessError); access.Path == name && access.Op == op {
+			return experimentalsys.EACCES
+		}
+	}
+	s.violations = append(s.violations, &AccessError{Plugin: s.plugin, Path: name, Op: op})
+	return experimentalsys.EACCES
+}
+
+// Returns the accesses which were denied, in the order they happened
+func (s *sandboxFS) Violations() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]error(nil), s.violations...)
+}
+
+func (s *sandboxFS) OpenFile(name string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	name = sandboxPath(name)
+	write_flags := experimentalsys.O_RDWR | experimentalsys.O_WRONLY | experimentalsys.O_CREAT | experimentalsys.O_TRUNC | experimentalsys.O_APPEND
+	if flag&write_flags != 0 {
+		if !s.writable[name] {
+			return nil, s.deny("write", name)
+		}
+		return s.fs.OpenFile(name, flag, perm)
+	}
+
+	if !s.canRead(name) {
+		return nil, s.deny("read", name)
+	}
+	file, errno := s.fs.OpenFile(name, flag, perm)
+	if errno != 0 || !s.isParent(name) {
+		return file, errno
+	}
+	return &sandboxDir{File: file, sandbox: s, name: name}, 0
+}
+
+func (s *sandboxFS) Lstat(name string) (sys.Stat_t, experimentalsys.Errno) {
+	if name = sandboxPath(name); !s.canRead(name) {
+		return sys.Stat_t{}, s.deny("read", name)
+	}
+	return s.fs.Lstat(name)
+}
+
+func (s *sandboxFS) Stat(name string) (sys.Stat_t, experimentalsys.Errno) {
+	if name = sandboxPath(name); !s.canRead(name) {
+		return sys.Stat_t{}, s.deny("read", name)
+	}
+	return s.fs.Stat(name)
+}
+
+func (s *sandboxFS) Readlink(name string) (string, experimentalsys.Errno) {
+	if name = sandboxPath(name); !s.canRead(name) {
+		return "", s.deny("read", name)
+	}
+	return s.fs.Readlink(name)
+}
+
+func (s *sandboxFS) Utimens(name string, atim, mtim int64) experimentalsys.Errno {
+	if name = sandboxPath(name); !s.writable[name] {
+		return s.deny("write", name)
+	}
+	return s.fs.Utimens(name, atim, mtim)
+}
+
+// Plugins may only modify the contents of their writable files, so changes to the directory structure are denied
+
+func (s *sandboxFS) Mkdir(name string, perm fs.FileMode) experimentalsys.Errno {
+	return s.deny("write", sandboxPath(name))
+}
+
+func (s *sandboxFS) Chmod(name string, perm fs.FileMode) experimentalsys.Errno {
+	return s.deny("write", sandboxPath(name))
+}
+
+func (s *sandboxFS) Rename(from, to string) experimentalsys.Errno {
+	return s.deny("write", sandboxPath(to))
+}
+
+func (s *sandboxFS) Rmdir(name string) experimentalsys.Errno {
+	return s.deny("write", sandboxPath(name))
+}
+
+func (s *sandboxFS) Unlink(name string) experimentalsys.Errno {
+	return s.deny("write", sandboxPath(name))
+}
+
+func (s *sandboxFS) Link(oldPath, newPath string) experimentalsys.Errno {
+	return s.deny("write", sandboxPath(newPath))
+}
+
+func (s *sandboxFS) Symlink(oldPath, linkName string) experimentalsys.Errno {
+	return s.deny("write", sandboxPath(linkName))
+}
+
+// A directory which only lists the entries visible within the sandbox
+type sandboxDir struct {
+	experimentalsys.File
+	sandbox *sandboxFS
+	name    string
+}
+
+func (d *sandboxDir) visible(dirent experimentalsys.Dirent) bool {
+	return d.sandbox.canRead(path.Join(d.name, dirent.Name))
+}
+
+// Reads entries one at a time so that no more than n visible entries are consumed from the underlying directory,
+// which keeps its position correct without buffering
+func (d *sandboxDir) Readdir(n int) ([]experimentalsys.Dirent, experimentalsys.Errno) {
+	var visible []experimentalsys.Dirent
+	if n <= 0 {
+		dirents, errno := d.File.Readdir(n)
+		for _, dirent := range dirents {
+			if d.visible(dirent) {
+				visible = append(visible, dirent)
+			}
+		}
+		return visible, errno
+	}
+
+	for len(visible) < n {
+		dirents, errno := d.File.Readdir(1)
+		if errno != 0 {
+			return visible, errno
+		}
+		if len(dirents) == 0 {
+			break
+		}
+		if d.visible(dirents[0]) {
+			visible = append(visible, dirents[0])
+		}
+	}
+	return visible, 0
+}
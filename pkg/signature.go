@@ -0,0 +1,106 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/minisign"
+)
+
+// Returned when a plugin can't be trusted, because it isn't pinned or signed by one of the trusted keys
+type TrustError struct {
+	Plugin string
+	Reason string
+}
+
+func (e *TrustError) Error() string {
+	return fmt.Sprintf("plugin %s is not trusted: %s", e.Plugin, e.Reason)
+}
+
+// Fails when the policy requires the plugin to be pinned and it isn't
+func checkPinned(_plugin config.Plugin, security *config.Security) error {
+	if _plugin.SHA256 == "" && security.IsStrict() {
+		return &TrustError{Plugin: _plugin.Name, Reason: "it isn't pinned to a sha256, which security.strict requires. Run `changeset plugin update` to pin it"}
+	}
+	return nil
+}
+
+// Returns where the signature of the plugin is read from, which is a URL or a path relative to the project root
+func signatureURL(_plugin config.Plugin, fallback string) string {
+	signature := _plugin.Signature
+	if signature == "" {
+		signature = fallback + ".minisig"
+	}
+	if !strings.Contains(signature, "://") {
+		signature = "file://" + signature
+	}
+	return signature
+}
+
+// Checks the plugin was signed by one of the trusted keys, reading the signature with the given function. Nothing
+// is checked when there are no trusted keys
+func verifySignature(_plugin config.Plugin, security *config.Security, contents []byte, read func(uri string) ([]byte, error)) error {
+	keys, err := security.PublicKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		if security.IsStrict() {
+			return &TrustError{Plugin: _plugin.Name, Reason: "security.strict requires signatures, but there are no security.trustedKeys"}
+		}
+		return nil
+	}
+
+	uri := signatureURL(_plugin, _plugin.URL)
+	if _plugin.IsExec() {
+		uri = signatureURL(_plugin, "file://"+strings.TrimPrefix(strings.TrimPrefix(_plugin.URL, "exec://"), "file://"))
+	}
+	signature_file, err := read(uri)
+	if err != nil {
+		return &TrustError{Plugin: _plugin.Name, Reason: fmt.Sprintf("failed to read its signature from %s: %v", uri, err)}
+	}
+	signature, err := minisign.ParseSignature(signature_file)
+	if err != nil {
+		return &TrustError{Plugin: _plugin.Name, Reason: err.Error()}
+	}
+	if err := minisign.Verify(keys, contents, signature); err != nil {
+		return &TrustError{Plugin: _plugin.Name, Reason: err.Error()}
+	}
+	return nil
+}
+
+// Checks the signature of the WASM plugin, using the copy of the signature cached alongside it when there is one.
+// Verified signatures are cached so that later runs don't need to fetch them
+func (r *Runner) verifySignature(ctx context.Context, cache string, wmod []byte, sum string) error {
+	cached := cachedPath(cache, sum) + ".minisig"
+	if contents, err := os.ReadFile(cached); err == nil {
+		err := verifySignature(r.Plugin, r.Security, wmod, func(string) ([]byte, error) { return contents, nil })
+		if err == nil {
+			return nil
+		}
+		// A cached signature which no longer verifies, e.g. after the trusted keys changed, is fetched again
+		os.Remove(cached)
+	}
+
+	var fetched []byte
+	err := verifySignature(r.Plugin, r.Security, wmod, func(uri string) ([]byte, error) {
+		var err error
+		fetched, _, _, err = r.fetch(ctx, uri)
+		return fetched, err
+	})
+	if err != nil || fetched == nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		return fmt.Errorf("mkdirall: %w", err)
+	}
+	if err := os.WriteFile(cached, fetched, 0644); err != nil {
+		return fmt.Errorf("cache signature: %w", err)
+	}
+	return nil
+}
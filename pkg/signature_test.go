@@ -0,0 +1,138 @@
+package wasm
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/internal/minisigntest"
+	"github.com/alex-way/changesets/pkg/minisign"
+	"github.com/alex-way/changesets/pkg/plugin"
+)
+
+type signingKey struct {
+	id      minisign.KeyID
+	private ed25519.PrivateKey
+	public  minisign.PublicKey
+}
+
+func newSigningKey(t *testing.T) signingKey {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	var id minisign.KeyID
+	_, err = rand.Read(id[:])
+	require.NoError(t, err)
+	return signingKey{id: id, private: private, public: minisign.PublicKey{ID: id, Key: public}}
+}
+
+// Signs the file, writing the signature next to it
+func (k signingKey) sign(t *testing.T, path string) {
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	signature := minisigntest.Sign(k.private, k.id, contents, "file:"+filepath.Base(path))
+	require.NoError(t, os.WriteFile(path+".minisig", signature, 0644))
+}
+
+func (k signingKey) security(strict bool) *config.Security {
+	return &config.Security{TrustedKeys: []string{k.public.String()}, Strict: strict}
+}
+
+func TestInstallVerifiesTheSignature(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := newProject(t)
+	path := filepath.Join(root, "plugin.wasm")
+	require.NoError(t, os.WriteFile(path, []byte("not really wasm"), 0644))
+	trusted := newSigningKey(t)
+
+	runner := &Runner{Plugin: config.Plugin{Name: "test", URL: "file://plugin.wasm"}, Root: root, Security: trusted.security(false)}
+	var trust_err *TrustError
+
+	_, err := runner.Install(context.Background(), false)
+	require.ErrorAs(t, err, &trust_err)
+	assert.ErrorContains(t, err, "failed to read its signature from file://plugin.wasm.minisig")
+
+	newSigningKey(t).sign(t, path)
+	_, err = runner.Install(context.Background(), false)
+	require.ErrorAs(t, err, &trust_err)
+	assert.ErrorContains(t, err, "which isn't trusted")
+
+	trusted.sign(t, path)
+	installed, err := runner.Install(context.Background(), false)
+	require.NoError(t, err)
+	assert.FileExists(t, installed.Path+".minisig")
+
+	// Modifying the plugin after it was signed invalidates the signature
+	require.NoError(t, os.WriteFile(path, []byte("tampered"), 0644))
+	_, err = runner.Install(context.Background(), false)
+	require.ErrorAs(t, err, &trust_err)
+	assert.ErrorContains(t, err, "signature verification failed")
+}
+
+func TestPluginsAreOnlyRunWhenSigned(t *testing.T) {
+	root := newProject(t)
+	trusted := newSigningKey(t)
+
+	// Built first, as a new home directory has an empty build cache
+	runner := newRunner(t, NewRegistry(), root)
+	t.Setenv("HOME", t.TempDir())
+	runner.Plugin.Signature = "plugin.minisig"
+	runner.Security = trusted.security(false)
+	client := plugin.NewVersionGetterSetterServiceClient(runner)
+
+	var trust_err *TrustError
+	_, err := client.Request(context.Background(), getVersionRequest())
+	require.ErrorAs(t, err, &trust_err)
+
+	contents, err := os.ReadFile(buildPlugin(t))
+	require.NoError(t, err)
+	signature := minisigntest.Sign(trusted.private, trusted.id, contents, "file:plugin.wasm")
+	require.NoError(t, os.WriteFile(filepath.Join(root, "plugin.minisig"), signature, 0644))
+
+	resp, err := client.Request(context.Background(), getVersionRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", resp.GetGetVersion().Version)
+}
+
+func TestStrictRequiresPinnedPlugins(t *testing.T) {
+	root := newProject(t)
+	runner := newRunner(t, NewRegistry(), root)
+	runner.Security = newSigningKey(t).security(true)
+
+	var trust_err *TrustError
+	_, err := plugin.NewVersionGetterSetterServiceClient(runner).Request(context.Background(), getVersionRequest())
+	require.ErrorAs(t, err, &trust_err)
+	assert.ErrorContains(t, err, "plugin test is not trusted: it isn't pinned to a sha256")
+
+	exec_runner := newExecRunner(t, root, config.Plugin{})
+	exec_runner.Security = runner.Security
+	_, err = exec_runner.Info(context.Background())
+	require.ErrorAs(t, err, &trust_err)
+}
+
+func TestExecPluginsAreOnlyRunWhenSigned(t *testing.T) {
+	root := newProject(t)
+	contents, err := os.ReadFile(buildNativePlugin(t))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "plugin"), contents, 0755))
+	trusted := newSigningKey(t)
+
+	runner := newExecRunner(t, root, config.Plugin{URL: "exec://./plugin"})
+	runner.Security = trusted.security(false)
+
+	var trust_err *TrustError
+	_, err = runner.Info(context.Background())
+	require.ErrorAs(t, err, &trust_err)
+	assert.ErrorContains(t, err, "failed to read its signature from file://./plugin.minisig")
+
+	trusted.sign(t, filepath.Join(root, "plugin"))
+	info, err := runner.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, plugin.PROTOCOL_VERSION, info.ProtocolVersion)
+}
@@ -0,0 +1,158 @@
+// A plugin used by the runner tests. It reads and writes the versioned file, and tries to access the files named
+// by its "read" and "write" settings so that the tests can check what the sandbox allows. The "loop", "allocate"
+// and "spam" settings misbehave so that the tests can check the limits of the runner, and "log" writes logs
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/alex-way/changesets/pkg/plugin"
+)
+
+// Accesses the files named by the settings, failing if any can't be accessed
+func probe(settings map[string]interface{}) error {
+	if message, ok := settings["log"].(string); ok {
+		logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		logger.Warn(message, "attempt", 1, "plugin", "impersonated")
+		logger.Debug("details")
+		fmt.Fprintln(os.Stderr, "not structured")
+	}
+	if path, ok := settings["read"].(string); ok {
+		if _, err := os.ReadFile(path); err != nil {
+			return err
+		}
+	}
+	if path, ok := settings["write"].(string); ok {
+		if err := os.WriteFile(path, []byte("written by plugin\n"), 0644); err != nil {
+			return err
+		}
+	}
+	if path, ok := settings["list"].(string); ok {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		fmt.Fprintln(os.Stderr, strings.Join(names, ","))
+	}
+	if loop, ok := settings["loop"].(bool); ok && loop {
+		for {
+		}
+	}
+	if mebibytes, ok := settings["allocate"].(float64); ok {
+		for i := 0; i < int(mebibytes); i++ {
+			chunk := make([]byte, 1<<20)
+			chunk[len(chunk)-1] = 1
+			retained = append(retained, chunk)
+		}
+	}
+	if size, ok := settings["spam"].(float64); ok {
+		os.Stdout.Write([]byte(strings.Repeat("x", int(size))))
+	}
+	return nil
+}
+
+// Set with -ldflags -X to build variants of the plugin which speak other protocol versions, or which predate GetInfo
+var protocol = ""
+var legacy = "false"
+
+// Keeps the memory allocated by the "allocate" setting reachable
+var retained [][]byte
+
+func handle(req *plugin.RequestMessage) (*plugin.Response, error) {
+	switch request := req.Request.(type) {
+	case *plugin.RequestMessage_GetInfo:
+		if legacy == "true" {
+			break
+		}
+		version := int(plugin.PROTOCOL_VERSION)
+		if protocol != "" {
+			var err error
+			if version, err = strconv.Atoi(protocol); err != nil {
+				return nil, err
+			}
+		}
+		return &plugin.Response{
+			Status: &plugin.Status{},
+			Response: &plugin.Response_GetInfo{GetInfo: &plugin.GetInfoResponse{
+				Name:            "test",
+				Version:         "1.0.0",
+				ProtocolVersion: uint32(version),
+				Capabilities:    []plugin.Capability{plugin.Capability_CAPABILITY_GET_VERSION, plugin.Capability_CAPABILITY_SET_VERSION},
+			}},
+		}, nil
+
+	case *plugin.RequestMessage_GetVersion:
+		if err := probe(request.GetVersion.Settings.AsMap()); err != nil {
+			return nil, err
+		}
+		contents, err := os.ReadFile(request.GetVersion.FilePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return &plugin.Response{Status: &plugin.Status{Code: int32(codes.NotFound), Message: err.Error()}}, nil
+		} else if err != nil {
+			return nil, err
+		}
+		version := strings.TrimSpace(string(contents))
+		if version == "" {
+			message := request.GetVersion.FilePath + " doesn't contain a version"
+			return &plugin.Response{Status: &plugin.Status{Code: int32(codes.InvalidArgument), Message: message}}, nil
+		}
+		return &plugin.Response{
+			Status:   &plugin.Status{},
+			Response: &plugin.Response_GetVersion{GetVersion: &plugin.GetVersionResponse{Version: version}},
+		}, nil
+
+	case *plugin.RequestMessage_SetVersion:
+		if err := probe(request.SetVersion.Settings.AsMap()); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(request.SetVersion.FilePath, []byte(request.SetVersion.Version+"\n"), 0644); err != nil {
+			return nil, err
+		}
+		return &plugin.Response{
+			Status:   &plugin.Status{},
+			Response: &plugin.Response_SetVersion{SetVersion: &plugin.SetVersionResponse{}},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported request %T", req.Request)
+}
+
+func main() {
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var req plugin.RequestMessage
+	if err := proto.Unmarshal(input, &req); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	resp, err := handle(&req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	output, err := proto.Marshal(resp)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(output)
+}
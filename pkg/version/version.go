@@ -31,19 +31,9 @@ func (bump_type BumpType) String() string {
 	return "none"
 }
 
+// Parses the name of a bump type, see Vocabulary.Parse for accepting aliases
 func ParseBumpType(s string) (BumpType, error) {
-	switch s {
-	case "major":
-		return Major, nil
-	case "minor":
-		return Minor, nil
-	case "patch":
-		return Patch, nil
-	case "none":
-		return None, nil
-	default:
-		return 0, errors.New("invalid bump type. Must be one of: major, minor, patch, none")
-	}
+	return Vocabulary{}.Parse(s)
 }
 
 const (
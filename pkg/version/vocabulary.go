@@ -0,0 +1,91 @@
+package version
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The bump types which can be chosen for a change, from most to least significant
+var BUMP_TYPES = []BumpType{Major, Minor, Patch, None}
+
+// Labels shown for each bump type when none are configured
+var DEFAULT_LABELS = map[BumpType]string{
+	Major: "Major",
+	Minor: "Minor",
+	Patch: "Patch",
+	None:  "Other",
+}
+
+// The words accepted for each bump type in addition to its name, and the labels used to display them.
+// The zero value accepts only the names and uses the default labels
+type Vocabulary struct {
+	// Extra words mapped to their bump type, e.g. "breaking" to Major
+	Aliases map[string]BumpType
+	// Labels replacing the defaults, e.g. "Breaking" for Major
+	Labels map[BumpType]string
+}
+
+// Builds a vocabulary from the aliases of each bump type, rejecting words which would be ambiguous
+func NewVocabulary(aliases map[BumpType][]string, labels map[BumpType]string) (Vocabulary, error) {
+	vocabulary := Vocabulary{Aliases: map[string]BumpType{}, Labels: map[BumpType]string{}}
+	for _, bump_type := range BUMP_TYPES {
+		for _, alias := range aliases[bump_type] {
+			word := strings.ToLower(strings.TrimSpace(alias))
+			if word == "" {
+				return Vocabulary{}, fmt.Errorf("empty alias for bump type %s", bump_type)
+			}
+			if existing, err := (Vocabulary{}).Parse(word); err == nil && existing != bump_type {
+				return Vocabulary{}, fmt.Errorf("alias %q of %s clashes with the bump type %s", alias, bump_type, existing)
+			}
+			if existing, ok := vocabulary.Aliases[word]; ok && existing != bump_type {
+				return Vocabulary{}, fmt.Errorf("alias %q is used by both %s and %s", alias, existing, bump_type)
+			}
+			vocabulary.Aliases[word] = bump_type
+		}
+		if label := labels[bump_type]; label != "" {
+			vocabulary.Labels[bump_type] = label
+		}
+	}
+	return vocabulary, nil
+}
+
+// Returns the accepted words for the bump type, starting with its name
+func (v Vocabulary) Words(bump_type BumpType) []string {
+	words := []string{bump_type.String()}
+	for word, aliased := range v.Aliases {
+		if aliased == bump_type {
+			words = append(words, word)
+		}
+	}
+	// Map iteration order is random, so keep the aliases sorted after the name
+	sort.Strings(words[1:])
+	return words
+}
+
+// Parses the name or an alias of a bump type, ignoring case
+func (v Vocabulary) Parse(s string) (BumpType, error) {
+	word := strings.ToLower(strings.TrimSpace(s))
+	for _, bump_type := range BUMP_TYPES {
+		if word == bump_type.String() {
+			return bump_type, nil
+		}
+	}
+	if bump_type, ok := v.Aliases[word]; ok {
+		return bump_type, nil
+	}
+
+	var words []string
+	for _, bump_type := range BUMP_TYPES {
+		words = append(words, v.Words(bump_type)...)
+	}
+	return 0, fmt.Errorf("invalid bump type %q. Must be one of: %s", s, strings.Join(words, ", "))
+}
+
+// Returns the label displayed for the bump type
+func (v Vocabulary) Label(bump_type BumpType) string {
+	if label, ok := v.Labels[bump_type]; ok {
+		return label
+	}
+	return DEFAULT_LABELS[bump_type]
+}
@@ -0,0 +1,47 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVocabularyParse(t *testing.T) {
+	vocabulary, err := NewVocabulary(map[BumpType][]string{
+		Major: {"breaking"},
+		Minor: {"feature", "feat"},
+		Patch: {"fix"},
+		None:  {"chore"},
+	}, nil)
+	assert.NoError(t, err)
+
+	for word, expected := range map[string]BumpType{"breaking": Major, "Feature": Minor, "feat": Minor, "fix": Patch, "chore": None, "major": Major} {
+		bump_type, err := vocabulary.Parse(word)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, bump_type, word)
+	}
+
+	_, err = vocabulary.Parse("docs")
+	assert.EqualError(t, err, `invalid bump type "docs". Must be one of: major, breaking, minor, feat, feature, patch, fix, none, chore`)
+}
+
+func TestVocabularyDefaults(t *testing.T) {
+	_, err := Vocabulary{}.Parse("breaking")
+	assert.EqualError(t, err, `invalid bump type "breaking". Must be one of: major, minor, patch, none`)
+	assert.Equal(t, "Other", Vocabulary{}.Label(None))
+}
+
+func TestVocabularyLabels(t *testing.T) {
+	vocabulary, err := NewVocabulary(nil, map[BumpType]string{Major: "Breaking"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Breaking", vocabulary.Label(Major))
+	assert.Equal(t, "Minor", vocabulary.Label(Minor))
+}
+
+func TestVocabularyAmbiguousAliases(t *testing.T) {
+	_, err := NewVocabulary(map[BumpType][]string{Major: {"change"}, Minor: {"change"}}, nil)
+	assert.ErrorContains(t, err, `alias "change" is used by both major and minor`)
+
+	_, err = NewVocabulary(map[BumpType][]string{Major: {"patch"}}, nil)
+	assert.ErrorContains(t, err, `clashes with the bump type patch`)
+}
@@ -7,18 +7,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"log/slog"
-	"net/http"
 	"os"
-	"os/user"
 	"path/filepath"
 	"strings"
 
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/sysfs"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 	"github.com/tetratelabs/wazero/sys"
-	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -29,137 +27,173 @@ import (
 	"github.com/alex-way/changesets/pkg/plugin"
 )
 
-var flight singleflight.Group
-
 type runtimeAndCode struct {
 	rt   wazero.Runtime
 	code wazero.CompiledModule
+	// What the plugin reported when it was loaded
+	info *plugin.GetInfoResponse
 }
 
 type Runner struct {
 	Plugin config.Plugin
+	// The project root, which is mounted into the plugin's filesystem and used to resolve relative file:// URLs
+	Root string
+	// Where compiled plugins are kept between requests, defaulting to DefaultRegistry
+	Registry *Registry
+	// Whether the plugin must be pinned and signed, verifying nothing when nil
+	Security *config.Security
+	// Where the logs of the plugin are forwarded, defaulting to slog.Default
+	Logger *slog.Logger
+	// Whether the plugin may be downloaded and where from, downloading it from its URL when nil
+	Network *config.Network
+	// The lockfile, whose checksum is enforced when the plugin isn't pinned in the config. Nothing is enforced when empty
+	Lock string
 }
 
-// RestrictedFS is a custom file system implementation that restricts access to a specific file.
-type RestrictedFS struct {
-	target string
-}
-
-// NewRestrictedFS creates a new instance of RestrictedFS for the specified directory and target file.
-func NewRestrictedFS(target string) fs.FS {
-	return &RestrictedFS{target: target}
-}
-
-// Open opens the specified file if it matches the target file; otherwise, returns an error.
-func (fsys *RestrictedFS) Open(name string) (fs.File, error) {
-	if name != "." && name != fsys.target {
-		return nil, fmt.Errorf("access denied to file: %s", name)
+func (r *Runner) registry() *Registry {
+	if r.Registry == nil {
+		return DefaultRegistry
 	}
+	return r.Registry
+}
 
-	// if the thing trying to be opened is a directory, return a readable file
-	if stat, err := os.Stat(name); err == nil && stat.IsDir() {
-		println("Opening a directory")
-		return os.Open(name)
-	}
-	println("Opening a file")
-	file, err := os.OpenFile(name, os.O_RDWR|os.O_APPEND, 0644)
-	if err != nil {
-		return nil, err
+func (r *Runner) logger() *slog.Logger {
+	if r.Logger == nil {
+		return slog.Default()
 	}
-	defer file.Close()
-	return file, nil
+	return r.Logger
 }
 
-func (fsys *RestrictedFS) Write(name string, data []byte) (int, error) {
-	return 0, fmt.Errorf("write not allowed")
+// Returns the directory mounted into the plugin's filesystem
+func (r *Runner) root() string {
+	if r.Root == "" {
+		return "."
+	}
+	return r.Root
 }
 
 // Attempts to fetch the wasm file from either a URL or a local file depending on the prefix of the URL
-// Returns the bytes of the wasm file, the sha256 of the wasm file, and any error
-func (r *Runner) fetch(ctx context.Context, uri string) ([]byte, string, error) {
+// Returns the bytes of the wasm file, the sha256 of the wasm file, where it was fetched from, and any error
+func (r *Runner) fetch(ctx context.Context, uri string) ([]byte, string, string, error) {
 	var body io.ReadCloser
+	var resolved string
 
 	switch {
 	case strings.HasPrefix(uri, "file://"):
-		file, err := os.Open(strings.TrimPrefix(uri, "file://"))
+		path := strings.TrimPrefix(uri, "file://")
+		if !filepath.IsAbs(path) && r.Root != "" {
+			path = filepath.Join(r.Root, path)
+		}
+		file, err := os.Open(path)
 		if err != nil {
-			return nil, "", fmt.Errorf("os.Open: %s %w", uri, err)
+			return nil, "", "", fmt.Errorf("os.Open: %s %w", uri, err)
 		}
 		body = file
+		// Local paths are recorded as configured, since they're resolved against the project root on every machine
+		resolved = uri
 
 	case strings.HasPrefix(uri, "https://"):
-		req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
-		if err != nil {
-			return nil, "", fmt.Errorf("http.Get: %s %w", uri, err)
-		}
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := r.download(ctx, uri)
 		if err != nil {
-			return nil, "", fmt.Errorf("http.Get: %s %w", r.Plugin.URL, err)
+			return nil, "", "", err
 		}
 		body = resp.Body
+		resolved = resp.Request.URL.String()
 
 	default:
-		return nil, "", fmt.Errorf("unknown scheme: %s", r.Plugin.URL)
+		return nil, "", "", fmt.Errorf("unknown scheme: %s", r.Plugin.URL)
 	}
 
 	defer body.Close()
 
 	wmod, err := io.ReadAll(body)
 	if err != nil {
-		return nil, "", fmt.Errorf("readall: %w", err)
+		return nil, "", "", fmt.Errorf("readall: %w", err)
 	}
 
 	sum := sha256.Sum256(wmod)
 	actual_sha := fmt.Sprintf("%x", sum)
 
-	return wmod, actual_sha, nil
+	return wmod, actual_sha, resolved, nil
+}
+
+// Fetches the plugin and returns its sha256, ignoring any checksum set in the config
+func (r *Runner) Checksum(ctx context.Context) (string, error) {
+	_, sum, _, err := r.fetch(ctx, r.Plugin.URL)
+	return sum, err
 }
 
-// Verify the provided sha256 is valid.
+// Returns the sha256 the plugin must have: its pinned checksum, the checksum it was locked to, or when neither is set,
+// the checksum of whatever its URL serves
 func (r *Runner) getChecksum(ctx context.Context) (string, error) {
 	if r.Plugin.SHA256 != "" {
 		return r.Plugin.SHA256, nil
 	}
-	// TODO: Add a log line here about something
-	_, sum, err := r.fetch(ctx, r.Plugin.URL)
+	if err := checkPinned(r.Plugin, r.Security); err != nil {
+		return "", err
+	}
+	locked, err := lockedChecksum(r.Plugin, r.Lock)
+	if err != nil {
+		return "", err
+	}
+	if locked != "" {
+		return locked, nil
+	}
+	// Relative file:// URLs depend on the root, so it's part of the key
+	key := fmt.Sprintf("%s %s", r.root(), r.Plugin.URL)
+	if sum, ok := r.registry().checksum(key); ok {
+		return sum, nil
+	}
+	sum, err := r.Checksum(ctx)
 	if err != nil {
 		return "", err
 	}
+	r.registry().setChecksum(key, sum)
 	slog.Warn("fetching WASM binary to calculate sha256. Set this value in your config file to prevent unneeded work", "sha256", sum)
 	return sum, nil
 }
 
-func (r *Runner) loadAndCompile(ctx context.Context) (*runtimeAndCode, error) {
+func (r *Runner) loadAndCompile(ctx context.Context, _limits limits) (*runtimeAndCode, error) {
 	expected_sha, err := r.getChecksum(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	currentUser, err := user.Current()
+	cacheDir, err := CacheDir()
 	if err != nil {
-		slog.Error("Error:", err)
 		return nil, err
 	}
-
-	home_dir := currentUser.HomeDir
-
-	cacheDir := filepath.Join(home_dir, ".cache", "changesets")
-	value, err, _ := flight.Do(expected_sha, func() (interface{}, error) {
-		return r.loadAndCompileWASM(ctx, cacheDir, expected_sha)
+	return r.registry().load(registryKey(expected_sha, _limits), func() (*runtimeAndCode, error) {
+		compiled, err := r.loadAndCompileWASM(ctx, cacheDir, expected_sha, _limits)
+		if err != nil {
+			return nil, err
+		}
+		compiled.info, err = handshake(r.Plugin.Name, func(req *plugin.RequestMessage, resp *plugin.Response) error {
+			return r.run(ctx, compiled, plugin.VersionGetterSetterService_Request_FullMethodName, req, resp, _limits)
+		})
+		if err != nil {
+			compiled.rt.Close(ctx)
+			return nil, err
+		}
+		return compiled, nil
 	})
+}
+
+// Returns what the plugin reported about itself when it was loaded
+func (r *Runner) Info(ctx context.Context) (*plugin.GetInfoResponse, error) {
+	_limits, err := pluginLimits(r.Plugin)
 	if err != nil {
 		return nil, err
 	}
-	data, ok := value.(*runtimeAndCode)
-	if !ok {
-		return nil, fmt.Errorf("returned value was not a compiled module")
+	compiled, err := r.loadAndCompile(ctx, _limits)
+	if err != nil {
+		return nil, err
 	}
-	return data, nil
+	return compiled.info, nil
 }
 
-func (r *Runner) loadAndCompileWASM(ctx context.Context, cache string, expected_sha string) (*runtimeAndCode, error) {
-	pluginDir := filepath.Join(cache, expected_sha)
-	pluginPath := filepath.Join(pluginDir, "plugin.wasm")
+func (r *Runner) loadAndCompileWASM(ctx context.Context, cache string, expected_sha string, _limits limits) (*runtimeAndCode, error) {
+	pluginPath := cachedPath(cache, expected_sha)
 	_, staterr := os.Stat(pluginPath)
 
 	uri := r.Plugin.URL
@@ -168,7 +202,7 @@ func (r *Runner) loadAndCompileWASM(ctx context.Context, cache string, expected_
 		uri = "file://" + pluginPath
 	}
 
-	wmod, actual_sha, err := r.fetch(ctx, uri)
+	wmod, actual_sha, _, err := r.fetch(ctx, uri)
 	if err != nil {
 		return nil, err
 	}
@@ -177,26 +211,32 @@ func (r *Runner) loadAndCompileWASM(ctx context.Context, cache string, expected_
 		return nil, fmt.Errorf("invalid checksum: expected %s, got %s", expected_sha, actual_sha)
 	}
 
+	if err := r.verifySignature(ctx, cache, wmod, actual_sha); err != nil {
+		return nil, err
+	}
+
 	if staterr != nil {
 		slog.Debug("plugin not cached, caching now")
-		err := os.MkdirAll(pluginDir, 0755)
-		if err != nil && !os.IsExist(err) {
-			return nil, fmt.Errorf("mkdirall: %w", err)
-		}
-		if err := os.WriteFile(pluginPath, wmod, 0444); err != nil {
-			return nil, fmt.Errorf("cache wasm: %w", err)
+		if err := writeCached(cache, actual_sha, wmod); err != nil {
+			return nil, err
 		}
 	}
 
-	wazeroCache, err := wazero.NewCompilationCacheWithDir(filepath.Join(cache, "wazero"))
+	wazeroCache, err := r.registry().compilationCache(func() (wazero.CompilationCache, error) {
+		return wazero.NewCompilationCacheWithDir(filepath.Join(cache, "wazero"))
+	})
 	if err != nil {
 		return nil, fmt.Errorf("wazero.NewCompilationCacheWithDir: %w", err)
 	}
 
-	config := wazero.NewRuntimeConfig().WithCompilationCache(wazeroCache)
+	config := wazero.NewRuntimeConfig().
+		WithCompilationCache(wazeroCache).
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(_limits.memoryPages)
 	rt := wazero.NewRuntimeWithConfig(ctx, config)
 
 	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		rt.Close(ctx)
 		return nil, fmt.Errorf("wasi_snapshot_preview1 instantiate: %w", err)
 	}
 
@@ -204,6 +244,10 @@ func (r *Runner) loadAndCompileWASM(ctx context.Context, cache string, expected_
 	// time during instantiation.
 	code, err := rt.CompileModule(ctx, wmod)
 	if err != nil {
+		rt.Close(ctx)
+		if minimumMemoryPages(ctx, wmod) > _limits.memoryPages {
+			return nil, _limits.memoryError(r.Plugin.Name)
+		}
 		return nil, fmt.Errorf("compile module: %w", err)
 	}
 
@@ -222,35 +266,76 @@ func (r *Runner) Invoke(ctx context.Context, method string, args any, reply any,
 		req = genReq
 	}
 
-	stdinBlob, err := proto.Marshal(req)
+	_limits, err := pluginLimits(r.Plugin)
 	if err != nil {
-		return fmt.Errorf("failed to encode codegen request: %w", err)
+		return err
 	}
 
-	runtimeAndCode, err := r.loadAndCompile(ctx)
+	compiled, err := r.loadAndCompile(ctx, _limits)
 	if err != nil {
+		var limit_err *LimitError
+		var trust_err *TrustError
+		var offline_err *OfflineError
+		if errors.As(err, &limit_err) || errors.As(err, &trust_err) || errors.As(err, &offline_err) {
+			return err
+		}
 		return fmt.Errorf("loadBytes: %w", err)
 	}
 
-	var stderr, stdout bytes.Buffer
+	if err := checkCapability(r.Plugin.Name, compiled.info, genReq); err != nil {
+		return err
+	}
+
+	return r.run(ctx, compiled, method, req, reply, _limits)
+}
+
+// Runs a fresh instance of the compiled plugin for a single request
+func (r *Runner) run(ctx context.Context, compiled *runtimeAndCode, method string, req protoreflect.ProtoMessage, reply any, _limits limits) error {
+	stdinBlob, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode codegen request: %w", err)
+	}
+
+	stderr := limitedBuffer{max: _limits.outputBytes}
+	stdout := limitedBuffer{max: _limits.outputBytes}
+	memory := newMemoryWatcher(_limits)
 
-	// fileSystem := NewRestrictedFS(r.Plugin.VersionedFile)
+	readable, writable := sandboxFiles(r.Plugin, req)
+	sandbox := newSandboxFS(r.root(), r.Plugin.Name, readable, writable)
+	fs_config := wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(sandbox, ".")
 
-	//List all files in the rooted directory
 	conf := wazero.NewModuleConfig().
 		WithName(r.Plugin.Name).
 		WithArgs("plugin.wasm", method).
 		WithStdin(bytes.NewReader(stdinBlob)).
 		WithStdout(&stdout).
-		WithStderr(&stderr).WithFSConfig(wazero.NewFSConfig().WithDirMount(".", "."))
+		WithStderr(&stderr).WithFSConfig(fs_config)
+
+	run_ctx, cancel := context.WithTimeout(experimental.WithMemoryAllocator(ctx, memory), _limits.timeout)
+	defer cancel()
 
-	result, err := runtimeAndCode.rt.InstantiateModule(ctx, runtimeAndCode.code, conf)
+	result, err := compiled.rt.InstantiateModule(run_ctx, compiled.code, conf)
 	if result != nil {
 		defer result.Close(ctx)
 	}
-	if cerr := checkError(err, stderr); cerr != nil {
+	if err != nil && errors.Is(run_ctx.Err(), context.DeadlineExceeded) {
+		return &LimitError{Plugin: r.Plugin.Name, Limit: LIMIT_TIMEOUT, Value: _limits.timeout.String()}
+	}
+	if stdout.exceeded || stderr.exceeded {
+		return &LimitError{Plugin: r.Plugin.Name, Limit: LIMIT_OUTPUT, Value: fmt.Sprintf("%d bytes", _limits.outputBytes)}
+	}
+	unstructured := forwardLogs(ctx, r.logger(), r.Plugin.Name, stderr.Bytes())
+	cerr := checkError(err, unstructured)
+	if cerr != nil && memory.exhausted() {
+		return _limits.memoryError(r.Plugin.Name)
+	}
+	if violations := sandbox.Violations(); len(violations) > 0 {
+		return errors.Join(violations...)
+	}
+	if cerr != nil {
 		return cerr
 	}
+	logUnstructured(ctx, r.logger(), r.Plugin.Name, unstructured)
 
 	stdoutBlob := stdout.Bytes()
 
@@ -266,11 +351,30 @@ func (r *Runner) Invoke(ctx context.Context, method string, args any, reply any,
 	return nil
 }
 
+// Returns the files the plugin may read and write for the request. Plugins can only read the versioned file,
+// and only write the file the request asks them to update
+func sandboxFiles(_plugin config.Plugin, req protoreflect.ProtoMessage) ([]string, []string) {
+	message, ok := req.(*plugin.RequestMessage)
+	if !ok {
+		return nil, nil
+	}
+
+	switch request := message.Request.(type) {
+	case *plugin.RequestMessage_GetVersion:
+		return []string{request.GetVersion.FilePath}, nil
+	case *plugin.RequestMessage_SetVersion:
+		return nil, []string{request.SetVersion.FilePath}
+	case *plugin.RequestMessage_WriteChangelog:
+		return []string{_plugin.VersionedFile}, []string{request.WriteChangelog.FilePath}
+	}
+	return nil, nil
+}
+
 func (r *Runner) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
 	return nil, status.Error(codes.Unimplemented, "")
 }
 
-func checkError(err error, stderr bytes.Buffer) error {
+func checkError(err error, stderr string) error {
 	if err == nil {
 		return err
 	}
@@ -281,9 +385,8 @@ func checkError(err error, stderr bytes.Buffer) error {
 		}
 	}
 
-	stderrBlob := stderr.String()
-	if len(stderrBlob) > 0 {
-		return errors.New(stderrBlob)
+	if len(stderr) > 0 {
+		return errors.New(stderr)
 	}
 	return fmt.Errorf("call: %w", err)
 }
@@ -0,0 +1,252 @@
+package wasm
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alex-way/changesets/pkg/config"
+	"github.com/alex-way/changesets/pkg/plugin"
+)
+
+var (
+	buildMu sync.Mutex
+	plugins = map[string]string{}
+)
+
+// Builds the test plugin in testdata/plugin for wasip1, once per test run
+func buildPlugin(t testing.TB) string {
+	return buildPluginWith(t, "")
+}
+
+// Builds a variant of the test plugin with the given -ldflags, once per test run
+func buildPluginWith(t testing.TB, ldflags string) string {
+	return buildPluginFor(t, "wasip1", "wasm", ldflags)
+}
+
+// Builds the test plugin as an executable for the current platform, once per test run
+func buildNativePlugin(t testing.TB) string {
+	return buildPluginFor(t, runtime.GOOS, runtime.GOARCH, "")
+}
+
+func buildPluginFor(t testing.TB, goos string, goarch string, ldflags string) string {
+	t.Helper()
+	buildMu.Lock()
+	defer buildMu.Unlock()
+	key := goos + "/" + goarch + " " + ldflags
+	if path, ok := plugins[key]; ok {
+		return path
+	}
+
+	dir, err := os.MkdirTemp("", "changesets-plugin")
+	if err != nil {
+		t.Skipf("failed to build the test plugin: %v", err)
+	}
+	path := filepath.Join(dir, "plugin")
+	if goos == "wasip1" {
+		path += ".wasm"
+	}
+	cmd := exec.Command(filepath.Join(runtime.GOROOT(), "bin", "go"), "build", "-ldflags", ldflags, "-o", path, "./testdata/plugin")
+	cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("failed to build the test plugin: %s", output)
+	}
+	plugins[key] = path
+	return path
+}
+
+// Creates a project containing a versioned file, a secret and a git directory
+func newProject(t testing.TB) string {
+	root := t.TempDir()
+	for path, contents := range map[string]string{
+		"VERSION":      "1.2.3\n",
+		"secret.txt":   "hunter2\n",
+		".git/config":  "[core]\n",
+		"CHANGELOG.md": "# Changelog\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(contents), 0644))
+	}
+	return root
+}
+
+func request(t *testing.T, root string, req *plugin.RequestMessage) (*plugin.Response, error) {
+	return requestWithLimits(t, root, nil, req)
+}
+
+func requestWithLimits(t *testing.T, root string, _limits *config.Limits, req *plugin.RequestMessage) (*plugin.Response, error) {
+	runner := &Runner{
+		Plugin: config.Plugin{Name: "test", URL: "file://" + buildPlugin(t), VersionedFile: "VERSION", Limits: _limits},
+		Root:   root,
+	}
+	return plugin.NewVersionGetterSetterServiceClient(runner).Request(context.Background(), req)
+}
+
+func getVersion(t *testing.T, root string, settings map[string]interface{}) (*plugin.Response, error) {
+	return getVersionWithLimits(t, root, nil, settings)
+}
+
+func getVersionWithLimits(t *testing.T, root string, _limits *config.Limits, settings map[string]interface{}) (*plugin.Response, error) {
+	value, err := plugin.NewSettings(settings)
+	require.NoError(t, err)
+	return requestWithLimits(t, root, _limits, &plugin.RequestMessage{Request: &plugin.RequestMessage_GetVersion{
+		GetVersion: &plugin.GetVersionRequest{FilePath: "VERSION", Settings: value},
+	}})
+}
+
+func setVersion(t *testing.T, root string, settings map[string]interface{}) (*plugin.Response, error) {
+	value, err := plugin.NewSettings(settings)
+	require.NoError(t, err)
+	return request(t, root, &plugin.RequestMessage{Request: &plugin.RequestMessage_SetVersion{
+		SetVersion: &plugin.SetVersionRequest{FilePath: "VERSION", Version: "2.0.0", Settings: value},
+	}})
+}
+
+func TestPluginCanReadAndWriteTheVersionedFile(t *testing.T) {
+	root := newProject(t)
+
+	resp, err := getVersion(t, root, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", resp.GetGetVersion().Version)
+
+	_, err = setVersion(t, root, nil)
+	require.NoError(t, err)
+	contents, err := os.ReadFile(filepath.Join(root, "VERSION"))
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0\n", string(contents))
+}
+
+func TestPluginCannotReadOtherFiles(t *testing.T) {
+	root := newProject(t)
+
+	for _, path := range []string{".git/config", "secret.txt", "../outside.txt"} {
+		_, err := getVersion(t, root, map[string]interface{}{"read": path})
+		var access *AccessError
+		require.ErrorAs(t, err, &access, path)
+		assert.Equal(t, "test", access.Plugin)
+		assert.Equal(t, "read", access.Op)
+		assert.ErrorContains(t, err, "plugin test was denied read access to")
+	}
+}
+
+func TestPluginCannotWriteDuringGetVersion(t *testing.T) {
+	root := newProject(t)
+
+	_, err := getVersion(t, root, map[string]interface{}{"write": "VERSION"})
+	assert.EqualError(t, err, "plugin test was denied write access to VERSION")
+
+	contents, err := os.ReadFile(filepath.Join(root, "VERSION"))
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3\n", string(contents))
+}
+
+func TestPluginCanOnlyWriteTheTarget(t *testing.T) {
+	root := newProject(t)
+
+	_, err := setVersion(t, root, map[string]interface{}{"write": "CHANGELOG.md"})
+	assert.EqualError(t, err, "plugin test was denied write access to CHANGELOG.md")
+
+	contents, err := os.ReadFile(filepath.Join(root, "CHANGELOG.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Changelog\n", string(contents))
+}
+
+func TestSandboxHidesOtherFilesFromListings(t *testing.T) {
+	sandbox := newSandboxFS(newProject(t), "test", []string{"VERSION"}, nil)
+
+	dir, errno := sandbox.OpenFile(".", 0, 0)
+	require.Zero(t, errno)
+	dirents, errno := dir.Readdir(-1)
+	require.Zero(t, errno)
+	require.Len(t, dirents, 1)
+	assert.Equal(t, "VERSION", dirents[0].Name)
+
+	_, errno = sandbox.OpenFile(".git", 0, 0)
+	assert.NotZero(t, errno)
+	assert.EqualError(t, sandbox.Violations()[0], "plugin test was denied read access to .git")
+}
+
+func TestPluginIsStoppedAfterItsTimeout(t *testing.T) {
+	_limits := &config.Limits{Timeout: "500ms"}
+
+	_, err := getVersionWithLimits(t, newProject(t), _limits, map[string]interface{}{"loop": true})
+	var limit *LimitError
+	require.ErrorAs(t, err, &limit)
+	assert.Equal(t, LIMIT_TIMEOUT, limit.Limit)
+	assert.EqualError(t, err, "plugin test exceeded its timeout limit of 500ms")
+}
+
+func TestPluginCannotGrowPastItsMemoryLimit(t *testing.T) {
+	_limits := &config.Limits{MemoryPages: 512}
+
+	_, err := getVersionWithLimits(t, newProject(t), _limits, map[string]interface{}{"allocate": 8})
+	require.NoError(t, err)
+
+	_, err = getVersionWithLimits(t, newProject(t), _limits, map[string]interface{}{"allocate": 64})
+	var limit *LimitError
+	require.ErrorAs(t, err, &limit)
+	assert.Equal(t, LIMIT_MEMORY, limit.Limit)
+	assert.EqualError(t, err, "plugin test exceeded its memory limit of 512 pages (32 MiB)")
+}
+
+func TestPluginCannotStartWithMoreMemoryThanItsLimit(t *testing.T) {
+	_, err := getVersionWithLimits(t, newProject(t), &config.Limits{MemoryPages: 1}, nil)
+	assert.EqualError(t, err, "plugin test exceeded its memory limit of 1 pages (0 MiB)")
+}
+
+func TestPluginOutputIsCapped(t *testing.T) {
+	_limits := &config.Limits{OutputBytes: 1024}
+
+	_, err := getVersionWithLimits(t, newProject(t), _limits, map[string]interface{}{"spam": 4096})
+	var limit *LimitError
+	require.ErrorAs(t, err, &limit)
+	assert.Equal(t, LIMIT_OUTPUT, limit.Limit)
+	assert.EqualError(t, err, "plugin test exceeded its output limit of 1024 bytes")
+}
+
+func infoOf(t *testing.T, ldflags string) (*plugin.GetInfoResponse, error) {
+	runner := &Runner{
+		Plugin:   config.Plugin{Name: "test", URL: "file://" + buildPluginWith(t, ldflags), VersionedFile: "VERSION"},
+		Root:     newProject(t),
+		Registry: NewRegistry(),
+	}
+	defer runner.Registry.Close(context.Background())
+	return runner.Info(context.Background())
+}
+
+func TestPluginInfoIsReadWhenLoaded(t *testing.T) {
+	info, err := infoOf(t, "")
+	require.NoError(t, err)
+	assert.Equal(t, plugin.PROTOCOL_VERSION, info.ProtocolVersion)
+	assert.Equal(t, "1.0.0", info.Version)
+	assert.True(t, info.Supports(plugin.Capability_CAPABILITY_GET_VERSION))
+	assert.False(t, info.Supports(plugin.Capability_CAPABILITY_WRITE_CHANGELOG))
+}
+
+func TestPluginsWithoutInfoSpeakTheFirstProtocol(t *testing.T) {
+	info, err := infoOf(t, "-X main.legacy=true")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), info.ProtocolVersion)
+	assert.True(t, info.Supports(plugin.Capability_CAPABILITY_GET_VERSION))
+	assert.True(t, info.Supports(plugin.Capability_CAPABILITY_SET_VERSION))
+	assert.False(t, info.Supports(plugin.Capability_CAPABILITY_WRITE_CHANGELOG))
+}
+
+func TestPluginWithANewerProtocolFailsFast(t *testing.T) {
+	_, err := infoOf(t, "-X main.protocol=99")
+	assert.ErrorContains(t, err, "plugin test speaks protocol version 99, but this version of changeset only supports up to 2")
+}
+
+func TestUnsupportedRequestsAreNotSent(t *testing.T) {
+	_, err := request(t, newProject(t), &plugin.RequestMessage{Request: &plugin.RequestMessage_WriteChangelog{
+		WriteChangelog: &plugin.WriteChangelogRequest{FilePath: "CHANGELOG.md", Version: "2.0.0"},
+	}})
+	assert.EqualError(t, err, "plugin test does not support write_changelog requests")
+}